@@ -0,0 +1,94 @@
+package tree
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// RowAction is an operation offered at the end of every row, e.g. delete or edit.
+type RowAction struct {
+	Name string
+	// Icon is rendered in the actions cell
+	Icon string
+}
+
+// RowActionMsg is emitted when a row action is activated.
+type RowActionMsg struct {
+	Node   Node
+	Action RowAction
+}
+
+// WithRowActions sets the actions offered at the end of every row, see SetRowActions.
+func WithRowActions(actions ...RowAction) Option {
+	return func(m *Model) {
+		m.rowActions = actions
+	}
+}
+
+// SetRowActions sets the actions offered in a cell at the end of every row.
+// NextAction and PrevAction focus them on the row under the cursor, and
+// Expand on a focused action emits a RowActionMsg. No actions remove the cell.
+func (m *Model) SetRowActions(actions ...RowAction) {
+	m.rowActions = actions
+	m.rowAction = -1
+	m.rerender()
+}
+
+// FocusedAction returns the focused action of the row under the cursor, if any.
+func (m Model) FocusedAction() (RowAction, bool) {
+	if m.rowAction < 0 || m.rowAction >= len(m.rowActions) {
+		return RowAction{}, false
+	}
+	return m.rowActions[m.rowAction], true
+}
+
+// cycleAction moves the focus to the next (dir = 1) or the previous (dir = -1)
+// action of the row, going through the row itself between the last and the first one.
+func (m *Model) cycleAction(dir int) {
+	if len(m.rowActions) == 0 || m.currentNode() == nil {
+		return
+	}
+	count := len(m.rowActions) + 1
+	// shifted by one, so the row itself is at zero
+	m.rowAction = (m.rowAction+1+dir+count)%count - 1
+	m.rerenderNode(m.cursor)
+}
+
+// activateAction emits the RowActionMsg of the focused action.
+func (m Model) activateAction() tea.Cmd {
+	action, ok := m.FocusedAction()
+	n := m.currentNode()
+	if !ok || n == nil {
+		return noop
+	}
+	return func() tea.Msg {
+		return RowActionMsg{Node: n, Action: action}
+	}
+}
+
+// actionsWidth returns the width of the actions cell, including the space before it.
+func (m Model) actionsWidth() int {
+	if len(m.rowActions) == 0 {
+		return 0
+	}
+	return lipgloss.Width(m.actionsCell(nil))
+}
+
+// actionsCell renders the icons of the actions, highlighting the focused one
+// if the node is under the cursor.
+func (m Model) actionsCell(n Node) string {
+	if len(m.rowActions) == 0 {
+		return ""
+	}
+	icons := make([]string, len(m.rowActions))
+	for i, a := range m.rowActions {
+		style := m.Styles.Line
+		if n != nil && isSelected(n) && i == m.rowAction {
+			style = m.Styles.FocusedAction
+		}
+		icons[i] = style.Render(a.Icon)
+	}
+	return " " + strings.Join(icons, " ")
+}
@@ -0,0 +1,37 @@
+package tree
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Activatable is an optional interface for leaves which can be executed,
+// e.g. the commands of a menu mixed with the branches one can navigate.
+// Such leaves are rendered with the Activatable style, and Expand on them
+// emits the message returned by Activate.
+type Activatable interface {
+	// Activate returns the message emitted when the node is activated,
+	// nil if there is nothing to do at the moment.
+	Activate() tea.Msg
+}
+
+// activatable returns the node as Activatable, if it's a leaf implementing it.
+func activatable(n Node) (Activatable, bool) {
+	a, ok := n.(Activatable)
+	return a, ok && isLeaf(n)
+}
+
+// Activate emits the message of the node under the cursor, if it's an Activatable leaf.
+func (m Model) Activate() tea.Cmd {
+	a, ok := activatable(m.currentNode())
+	if !ok {
+		return noop
+	}
+	// evaluated right away, the node might change before the command runs
+	msg := a.Activate()
+	if msg == nil {
+		return noop
+	}
+	return func() tea.Msg {
+		return msg
+	}
+}
@@ -0,0 +1,45 @@
+package tree
+
+import (
+	"regexp"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ansiSequence matches the escape sequences which don't take up any room on the screen,
+// e.g. the colors (CSI) and the hyperlinks (OSC) the names or prefixes of the nodes might contain.
+var ansiSequence = regexp.MustCompile("\x1b(\\[[0-9;?]*[ -/]*[@-~]|\\][^\x07\x1b]*(\x07|\x1b\\\\))")
+
+// stripANSI removes all of the escape sequences from the text.
+func stripANSI(s string) string {
+	return ansiSequence.ReplaceAllString(s, "")
+}
+
+// textWidth returns the width of the widest line of the text on the screen,
+// unlike lipgloss.Width it ignores the hyperlinks too.
+func textWidth(s string) int {
+	return lipgloss.Width(stripANSI(s))
+}
+
+// isANSI reports whether the token returned by splitANSI is an escape sequence.
+func isANSI(token string) bool {
+	loc := ansiSequence.FindStringIndex(token)
+	return loc != nil && loc[0] == 0 && loc[1] == len(token)
+}
+
+// splitANSI splits the text into the escape sequences and the printable text between them.
+func splitANSI(s string) []string {
+	tokens := []string{}
+	last := 0
+	for _, loc := range ansiSequence.FindAllStringIndex(s, -1) {
+		if loc[0] > last {
+			tokens = append(tokens, s[last:loc[0]])
+		}
+		tokens = append(tokens, s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	if last < len(s) {
+		tokens = append(tokens, s[last:])
+	}
+	return tokens
+}
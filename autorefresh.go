@@ -0,0 +1,72 @@
+package tree
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// RefreshedMsg should be returned by the command of the loader given to
+// AutoRefresh, the nodes of the tree are replaced with its Nodes, see SetNodes.
+// Nil Nodes, e.g. when the loading failed, leave the tree as it is.
+// It's also the Patch replacing all of the nodes of a Source.
+type RefreshedMsg struct {
+	Nodes Nodes
+}
+
+// autoRefreshMsg is sent every interval, ticks of the replaced loaders are ignored.
+type autoRefreshMsg struct {
+	id int
+}
+
+type autoRefresh struct {
+	id       int
+	interval time.Duration
+	load     func() tea.Cmd
+	loading  bool // a load has been started, but its RefreshedMsg hasn't arrived yet
+}
+
+// AutoRefresh calls refresh every interval, whose command should return a
+// RefreshedMsg with the freshly loaded nodes, e.g. for monitoring processes.
+// A new load isn't started until the RefreshedMsg of the previous one arrives,
+// so the slow loaders skip the ticks instead of piling up.
+// The returned command starts the ticking, zero interval or nil refresh stops it.
+func (m *Model) AutoRefresh(interval time.Duration, refresh func() tea.Cmd) tea.Cmd {
+	m.autoRefresh = autoRefresh{
+		id:       m.autoRefresh.id + 1,
+		interval: interval,
+		load:     refresh,
+	}
+	if interval <= 0 || refresh == nil {
+		m.autoRefresh.load = nil
+		return noop
+	}
+	return m.autoRefresh.tick()
+}
+
+func (a autoRefresh) tick() tea.Cmd {
+	id := a.id
+	return tea.Tick(a.interval, func(time.Time) tea.Msg {
+		return autoRefreshMsg{id: id}
+	})
+}
+
+func (m *Model) updateAutoRefresh(msg autoRefreshMsg) tea.Cmd {
+	if msg.id != m.autoRefresh.id || m.autoRefresh.load == nil {
+		return noop
+	}
+	if m.autoRefresh.loading {
+		return m.autoRefresh.tick()
+	}
+	m.autoRefresh.loading = true
+	return tea.Batch(m.autoRefresh.load(), m.autoRefresh.tick())
+}
+
+func (m *Model) applyRefreshed(msg RefreshedMsg) tea.Cmd {
+	m.autoRefresh.loading = false
+	if msg.Nodes == nil {
+		return noop
+	}
+	m.SetNodes(msg.Nodes)
+	return m.hydrateVisible()
+}
@@ -0,0 +1,62 @@
+package tree
+
+// ChildCount determines which children are counted in the badges of the collapsed nodes.
+type ChildCount int
+
+const (
+	// ChildCountNone shows no badges
+	ChildCountNone ChildCount = iota
+	// ChildCountDirect counts only the children of the node
+	ChildCountDirect
+	// ChildCountRecursive counts all of the nodes below the node
+	ChildCountRecursive
+)
+
+// WithChildCount shows the child-count badges, see SetChildCount.
+func WithChildCount(cc ChildCount) Option {
+	return func(m *Model) {
+		m.childCount = cc
+	}
+}
+
+// SetChildCount sets which children are counted in the badges rendered
+// next to the names of the collapsed nodes, e.g. `src (37)`.
+// Hidden nodes are not counted.
+func (m *Model) SetChildCount(cc ChildCount) {
+	m.childCount = cc
+	m.rerender()
+}
+
+// countChildren returns the number of the visible nodes counted in the badge of the node.
+func (m Model) countChildren(n Node) int {
+	if m.childCount == ChildCountRecursive {
+		return len(n.Children().ordered(nil))
+	}
+	return len(n.Children().visible())
+}
+
+// renderBadge returns the styled child-count badge of the node preceded by a space,
+// empty if it has none.
+func (m Model) renderBadge(n Node) string {
+	if m.childCount == ChildCountNone || isPlaceholder(n) || !isCollapsible(n) || isExpanded(n) {
+		return ""
+	}
+	text := m.tr(TextChildCount, m.countChildren(n))
+	if text == "" {
+		return ""
+	}
+	return " " + m.Styles.ChildCount.Render(text)
+}
+
+// renderNameWithBadge renders the name cell with the badge right after the name,
+// the width excludes the badge.
+func (m Model) renderNameWithBadge(n Node, name, badge string, width int) string {
+	if badge == "" {
+		return m.renderName(n, name, width)
+	}
+	w := textWidth(name)
+	fill := max(width-w, 0)
+	left := int(float64(fill) * float64(m.alignment(n).Name))
+	base := m.nodeStyle(n).Copy().UnsetWidth().UnsetMaxWidth().UnsetAlign()
+	return pad(base, left) + m.renderName(n, name, w) + badge + pad(base, fill-left)
+}
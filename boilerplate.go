@@ -8,7 +8,34 @@ import (
 var (
 	defaultStyle         = lipgloss.NewStyle()
 	defaultSelectedStyle = defaultStyle.Reverse(true)
+	defaultMarkedStyle   = defaultStyle.Bold(true)
+	defaultPinnedStyle   = defaultStyle.Italic(true)
+	defaultMatchStyle    = defaultStyle.Underline(true)
+	defaultFilterStyle   = defaultStyle.Faint(true)
+	defaultActionStyle   = defaultStyle.Foreground(lipgloss.Color("6"))
+	defaultEmptyStyle    = defaultStyle.Faint(true)
+	defaultWarningStyle  = defaultStyle.Foreground(lipgloss.Color("3"))
+	defaultErrorStyle    = defaultStyle.Foreground(lipgloss.Color("1"))
+	defaultActionFocus   = defaultStyle.Reverse(true)
+	defaultStatusStyle   = defaultStyle.Faint(true)
+	defaultIconStyle     = defaultStyle
+	defaultIndicator     = defaultStyle
+	defaultAltLineStyle  = defaultStyle.Background(lipgloss.AdaptiveColor{Light: "254", Dark: "235"})
 	defaultSymbolStyle   = defaultStyle
+	defaultLineNumber    = defaultStyle.Faint(true)
+	defaultCurrentLine   = defaultStyle.Bold(true)
+	defaultChildCount    = defaultStyle.Faint(true)
+	defaultDescription   = defaultStyle.Faint(true)
+	defaultHeader        = defaultStyle.Bold(true)
+	defaultSuffix        = defaultStyle.Faint(true)
+	defaultTrack         = defaultStyle.Faint(true)
+	defaultThumb         = defaultStyle
+	defaultSticky        = defaultStyle
+	defaultTitle         = defaultStyle.Bold(true)
+	defaultLoading       = defaultStyle
+	defaultAdded         = defaultStyle.Foreground(lipgloss.Color("2"))
+	defaultChanged       = defaultStyle.Foreground(lipgloss.Color("3"))
+	defaultRemoved       = defaultStyle.Foreground(lipgloss.Color("1")).Strikethrough(true)
 )
 
 // KeyMap defines keybindings.
@@ -24,7 +51,60 @@ type KeyMap struct {
 	GotoBottom   key.Binding
 	ToggleFocus  key.Binding
 
-	Expand key.Binding
+	// Expand toggles the node under the cursor, or activates it if it's an Activatable leaf
+	Expand     key.Binding
+	ToggleMark key.Binding
+	TogglePin  key.Binding
+	// NextAction and PrevAction focus the actions at the end of the row, see SetRowActions
+	NextAction key.Binding
+	PrevAction key.Binding
+	// ToggleHidden shows or hides the nodes hidden by the predicate, see SetHiddenPredicate
+	ToggleHidden key.Binding
+	// ToggleLineNumbers cycles through the modes of the line-number gutter, see SetLineNumbers
+	ToggleLineNumbers key.Binding
+
+	// HistoryBack jumps back to the previously visited node
+	HistoryBack key.Binding
+	// ZoomIn shows only the subtree of the current node, ZoomOut goes back, see ZoomIn
+	ZoomIn  key.Binding
+	ZoomOut key.Binding
+
+	Undo key.Binding
+	Redo key.Binding
+	// UndoExpansion reverts only the last expand or collapse
+	UndoExpansion key.Binding
+
+	// CopyPath copies the path of the current node to the clipboard
+	CopyPath key.Binding
+	// Rename starts editing the name of the current node
+	Rename key.Binding
+
+	// ContextMenu asks the host for the context menu of the current node
+	ContextMenu key.Binding
+
+	// MoveNodeUp and MoveNodeDown swap the current node with its sibling
+	MoveNodeUp   key.Binding
+	MoveNodeDown key.Binding
+
+	// Command opens the command prompt
+	Command key.Binding
+	// Filter focuses the filter bar, if it's enabled, in which case it takes
+	// precedence over Search bound to the same key
+	Filter key.Binding
+	// Search opens the search prompt, NextMatch and PrevMatch cycle through the matches
+	Search    key.Binding
+	NextMatch key.Binding
+	PrevMatch key.Binding
+	// Confirm and Cancel are used while the prompt is open
+	Confirm key.Binding
+	Cancel  key.Binding
+	// PrevQuery and NextQuery recall the previous search and filter queries while the prompt is open
+	PrevQuery key.Binding
+	NextQuery key.Binding
+	// ToggleRegexp toggles matching the queries as regular expressions while the prompt is open
+	ToggleRegexp key.Binding
+	// ToggleCase cycles through the case modes while the search prompt is open, see SetCaseMode
+	ToggleCase key.Binding
 }
 
 // DefaultKeyMap returns a default set of keybindings.
@@ -47,8 +127,8 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("f/pgdn", "page down"),
 		),
 		HalfPageUp: key.NewBinding(
-			key.WithKeys("u", "ctrl+u"),
-			key.WithHelp("u", "½ page up"),
+			key.WithKeys("ctrl+u"),
+			key.WithHelp("ctrl+u", "½ page up"),
 		),
 		HalfPageDown: key.NewBinding(
 			key.WithKeys("d", "ctrl+d"),
@@ -66,6 +146,118 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "toggle expand for current node"),
 		),
+		ToggleMark: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "toggle mark for current node"),
+		),
+		TogglePin: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "toggle pin for current node"),
+		),
+		NextAction: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "next row action"),
+		),
+		PrevAction: key.NewBinding(
+			key.WithKeys("shift+tab"),
+			key.WithHelp("shift+tab", "previous row action"),
+		),
+		ToggleHidden: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "toggle hidden nodes"),
+		),
+		ToggleLineNumbers: key.NewBinding(
+			key.WithKeys("#"),
+			key.WithHelp("#", "toggle line numbers"),
+		),
+		HistoryBack: key.NewBinding(
+			key.WithKeys("ctrl+o", "backspace"),
+			key.WithHelp("ctrl+o", "jump back"),
+		),
+		ZoomIn: key.NewBinding(
+			key.WithKeys("z"),
+			key.WithHelp("z", "zoom in"),
+		),
+		ZoomOut: key.NewBinding(
+			key.WithKeys("Z"),
+			key.WithHelp("Z", "zoom out"),
+		),
+		Undo: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "undo"),
+		),
+		Redo: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "redo"),
+		),
+		UndoExpansion: key.NewBinding(
+			key.WithKeys("U"),
+			key.WithHelp("U", "undo expand/collapse"),
+		),
+		CopyPath: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy path"),
+		),
+		Rename: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "rename"),
+		),
+		ContextMenu: key.NewBinding(
+			key.WithKeys("."),
+			key.WithHelp(".", "context menu"),
+		),
+		MoveNodeUp: key.NewBinding(
+			key.WithKeys("alt+k", "alt+up"),
+			key.WithHelp("alt+k", "move node up"),
+		),
+		MoveNodeDown: key.NewBinding(
+			key.WithKeys("alt+j", "alt+down"),
+			key.WithHelp("alt+j", "move node down"),
+		),
+		Command: key.NewBinding(
+			key.WithKeys(":"),
+			key.WithHelp(":", "command prompt"),
+		),
+		Filter: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "filter"),
+		),
+		Search: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "search"),
+		),
+		NextMatch: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next match"),
+		),
+		PrevMatch: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "previous match"),
+		),
+		Confirm: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "confirm"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+		PrevQuery: key.NewBinding(
+			key.WithKeys("up"),
+			key.WithHelp("↑", "previous query"),
+		),
+		NextQuery: key.NewBinding(
+			key.WithKeys("down"),
+			key.WithHelp("↓", "next query"),
+		),
+		ToggleRegexp: key.NewBinding(
+			key.WithKeys("alt+r"),
+			key.WithHelp("alt+r", "toggle regexp"),
+		),
+		ToggleCase: key.NewBinding(
+			key.WithKeys("alt+c"),
+			key.WithHelp("alt+c", "toggle case sensitivity"),
+		),
 	}
 }
 
@@ -88,17 +280,109 @@ func (s Style) Width(w int) DepthStyler {
 // Styles contains style definitions for this list component. By default, these
 // values are generated by DefaultStyles.
 type Styles struct {
-	Line     lipgloss.Style
+	Line lipgloss.Style
+	// AltLine is used instead of Line for every other row, see SetStriping
+	AltLine lipgloss.Style
+	// Selected is used for the row under the cursor
 	Selected lipgloss.Style
-	Symbol   DepthStyler
+	// Marked is used for the rows marked by the user
+	Marked lipgloss.Style
+	// SelectedMarked is used for a marked row under the cursor
+	SelectedMarked lipgloss.Style
+	// Pinned is used for the rows in the pinned section at the top of the tree
+	Pinned lipgloss.Style
+	// MatchHighlight is used for the parts of the names matching the search or filter query,
+	// on top of the style of the row
+	MatchHighlight lipgloss.Style
+	// FilterBar is used for the filter bar, when it is not being edited
+	FilterBar lipgloss.Style
+	// Activatable is used for the leaves implementing Activatable,
+	// unless they're selected or marked
+	Activatable lipgloss.Style
+	// Placeholder is used for the row shown below an expanded node without children
+	Placeholder lipgloss.Style
+	// Warning and Error are used for the nodes with the respective Severity,
+	// Error also for the nodes marked with NodeError
+	Warning lipgloss.Style
+	Error   lipgloss.Style
+	// FocusedAction is used for the focused icon in the actions cell, see SetRowActions
+	FocusedAction lipgloss.Style
+	// StatusLine is used for the status line below the tree, see SetStatusLine and SetStatus
+	StatusLine lipgloss.Style
+	// Icon is used for the icons of the nodes implementing NodeIcon
+	Icon lipgloss.Style
+	// Indicator is used for the expand and collapse indicators, see Indicators
+	Indicator lipgloss.Style
+	// LineNumber is used for the numbers in the gutter, CurrentLineNumber for the one of the row
+	// under the cursor, see SetLineNumbers
+	LineNumber        lipgloss.Style
+	CurrentLineNumber lipgloss.Style
+	// ChildCount is used for the badges of the collapsed nodes, see SetChildCount
+	ChildCount lipgloss.Style
+	// Description is used for the descriptions of the nodes implementing Describer,
+	// on top of the style of the row
+	Description lipgloss.Style
+	// Header is used for the header row of the columns, see SetColumns
+	Header lipgloss.Style
+	// Suffix is used for the suffixes of the nodes implementing Suffixer, on top of the style of the row
+	Suffix lipgloss.Style
+	// ScrollbarTrack and ScrollbarThumb are used for the scrollbar, see SetScrollbar
+	ScrollbarTrack lipgloss.Style
+	ScrollbarThumb lipgloss.Style
+	// Sticky is used for the ancestors stuck at the top of the tree, see SetStickyAncestors
+	Sticky lipgloss.Style
+	// Title is used for the title bar above the tree, see SetTitle
+	Title lipgloss.Style
+	// EmptyTree is used for the message shown in place of the tree while it has no nodes to show,
+	// see SetEmptyMessage
+	EmptyTree lipgloss.Style
+	// Loading is used for the spinner and the message shown while the tree is loading, see SetLoading
+	Loading lipgloss.Style
+	// Added, Changed and Removed are used for the nodes changed by UpdateNodes, until the changes settle
+	Added   lipgloss.Style
+	Changed lipgloss.Style
+	Removed lipgloss.Style
+	Symbol  DepthStyler
+	// SymbolByDepth, if set, is used for the tree symbols instead of Symbol,
+	// e.g. for coloring every level of the indentation differently, see DepthPalette
+	SymbolByDepth func(depth int) lipgloss.Style
 }
 
 // DefaultStyles returns a set of default style definitions for this tree.
 func DefaultStyles() Styles {
 	return Styles{
-		Line:     defaultStyle,
-		Selected: defaultSelectedStyle,
-		Symbol:   Style(defaultSymbolStyle),
+		Line:              defaultStyle,
+		AltLine:           defaultAltLineStyle,
+		Selected:          defaultSelectedStyle,
+		Marked:            defaultMarkedStyle,
+		SelectedMarked:    defaultSelectedStyle.Copy().Inherit(defaultMarkedStyle),
+		Pinned:            defaultPinnedStyle,
+		MatchHighlight:    defaultMatchStyle,
+		FilterBar:         defaultFilterStyle,
+		Activatable:       defaultActionStyle,
+		Placeholder:       defaultEmptyStyle,
+		Warning:           defaultWarningStyle,
+		Error:             defaultErrorStyle,
+		FocusedAction:     defaultActionFocus,
+		StatusLine:        defaultStatusStyle,
+		Icon:              defaultIconStyle,
+		Indicator:         defaultIndicator,
+		LineNumber:        defaultLineNumber,
+		CurrentLineNumber: defaultCurrentLine,
+		ChildCount:        defaultChildCount,
+		Description:       defaultDescription,
+		Header:            defaultHeader,
+		Suffix:            defaultSuffix,
+		ScrollbarTrack:    defaultTrack,
+		ScrollbarThumb:    defaultThumb,
+		Sticky:            defaultSticky,
+		Title:             defaultTitle,
+		EmptyTree:         defaultEmptyStyle,
+		Loading:           defaultLoading,
+		Added:             defaultAdded,
+		Changed:           defaultChanged,
+		Removed:           defaultRemoved,
+		Symbol:            Style(defaultSymbolStyle),
 	}
 }
 
@@ -106,3 +390,27 @@ func draw(style DepthStyler, s string, width int, depth int) string {
 	return style.Width(width).Render(depth, s)
 }
 
+// defaultPalette colors the levels of the tree in the order of the rainbow.
+var defaultPalette = []lipgloss.TerminalColor{
+	lipgloss.Color("1"), lipgloss.Color("3"), lipgloss.Color("2"),
+	lipgloss.Color("6"), lipgloss.Color("4"), lipgloss.Color("5"),
+}
+
+// DepthPalette returns a Styles.SymbolByDepth which cycles through the colors
+// across the levels of the tree, like the rainbow indent guides of the editors.
+// No colors use the default rainbow palette.
+func DepthPalette(colors ...lipgloss.TerminalColor) func(depth int) lipgloss.Style {
+	if len(colors) == 0 {
+		colors = defaultPalette
+	}
+	return func(depth int) lipgloss.Style {
+		return defaultSymbolStyle.Copy().Foreground(colors[depth%len(colors)])
+	}
+}
+
+// WithDepthPalette colors the tree symbols by their depth, see DepthPalette.
+func WithDepthPalette(colors ...lipgloss.TerminalColor) Option {
+	return func(m *Model) {
+		m.Styles.SymbolByDepth = DepthPalette(colors...)
+	}
+}
@@ -0,0 +1,63 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// errNilNode is reported for the nil nodes, e.g. returned among the children of a node.
+var errNilNode = errors.New("nil node")
+
+// Err returns the problems with the nodes the model ran into since the last refresh,
+// e.g. a node panicking while it's being rendered, or nil. The broken nodes are
+// rendered as such instead of crashing the program, see TextBrokenNode.
+func (m Model) Err() error {
+	return errors.Join(m.errs...)
+}
+
+// isNil reports whether the node is nil, or a nil pointer wrapped in the interface.
+func isNil(n Node) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// safeRender renders the node, or the broken row if it's nil or it panics, recording the problem.
+func (m *Model) safeRender(n Node) (rendered string) {
+	if isNil(n) {
+		m.report(n, errNilNode)
+		return m.brokenRow()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			m.report(n, fmt.Errorf("rendering %T: %v", n, r))
+			rendered = m.brokenRow()
+		}
+	}()
+	return m.renderNode(n)
+}
+
+// report records the problem with the node, once per refresh,
+// since the nodes get rendered again whenever they're scrolled into view.
+func (m *Model) report(n Node, err error) {
+	if m.broken[n] {
+		return
+	}
+	if m.broken == nil {
+		m.broken = map[Node]bool{}
+	}
+	m.broken[n] = true
+	m.errs = append(m.errs, err)
+}
+
+// brokenRow renders the row shown in place of a broken node.
+func (m Model) brokenRow() string {
+	return m.Styles.Error.Render(m.fitWidth(m.tr(TextBrokenNode)))
+}
@@ -0,0 +1,67 @@
+package tree
+
+// Builder builds the nodes of simple menus and static trees, which don't need
+// a Node implementation of their own, e.g.
+//
+//	tree.New(tree.Build(
+//		tree.Root("~").Child(
+//			tree.Branch("src", tree.Leaf("main.go")),
+//			tree.Leaf("go.mod"),
+//		),
+//	))
+//
+// The nodes are SimpleNodes with their parents wired up, the branches are collapsible and expanded.
+type Builder struct {
+	node *SimpleNode
+}
+
+// Root starts a top level node, it's the same as Branch.
+func Root(name string, children ...*Builder) *Builder {
+	return Branch(name, children...)
+}
+
+// Branch builds a collapsible node with the given children, or a placeholder if there are none.
+func Branch(name string, children ...*Builder) *Builder {
+	b := &Builder{node: NewSimpleNode(name)}
+	b.node.state |= NodeCollapsible
+	return b.Child(children...)
+}
+
+// Leaf builds a node without children.
+func Leaf(name string) *Builder {
+	return &Builder{node: NewSimpleNode(name)}
+}
+
+// Child appends the children to the node.
+func (b *Builder) Child(children ...*Builder) *Builder {
+	for _, c := range children {
+		b.node.Append(c.node)
+	}
+	return b
+}
+
+// Prefix sets the prefix of the node, see Node.
+func (b *Builder) Prefix(prefix string) *Builder {
+	b.node.SetPrefix(prefix)
+	return b
+}
+
+// Collapsed collapses the node, unless an expansion policy says otherwise, see WithExpansion.
+func (b *Builder) Collapsed() *Builder {
+	b.node.state |= NodeCollapsed
+	return b
+}
+
+// Node returns the built node.
+func (b *Builder) Node() *SimpleNode {
+	return b.node
+}
+
+// Build returns the built nodes, to be passed to New or SetNodes as the top level ones.
+func Build(roots ...*Builder) Nodes {
+	ns := make(Nodes, len(roots))
+	for i, b := range roots {
+		ns[i] = b.node
+	}
+	return ns
+}
@@ -0,0 +1,76 @@
+package tree
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CaseMode determines whether the queries are case sensitive.
+type CaseMode int
+
+const (
+	// CaseSmart ignores the case, unless the query contains an upper case letter
+	CaseSmart CaseMode = iota
+	// CaseSensitive never ignores the case
+	CaseSensitive
+	// CaseInsensitive always ignores the case
+	CaseInsensitive
+)
+
+// SetCaseMode sets whether the search and filter queries are case sensitive, CaseSmart by default.
+func (m *Model) SetCaseMode(c CaseMode) {
+	m.caseMode = c
+	m.prompt.Prompt = m.searchPrompt()
+}
+
+// CaseMode returns whether the search and filter queries are case sensitive.
+func (m Model) CaseMode() CaseMode {
+	return m.caseMode
+}
+
+// cycleCaseMode switches to the next case mode, wrapping around.
+func (m *Model) cycleCaseMode() {
+	m.SetCaseMode((m.caseMode + 1) % (CaseInsensitive + 1))
+}
+
+// ignoreCase reports whether the case should be ignored when matching the query.
+func (m Model) ignoreCase(query string) bool {
+	switch m.caseMode {
+	case CaseSensitive:
+		return false
+	case CaseInsensitive:
+		return true
+	}
+	return strings.IndexFunc(query, unicode.IsUpper) == -1
+}
+
+// foldCase lower cases the runes which keep their width in bytes, so the
+// positions of the matches within the folded text apply to the original one.
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if l := unicode.ToLower(r); utf8.RuneLen(l) == utf8.RuneLen(r) {
+			return l
+		}
+		return r
+	}, s)
+}
+
+// searchPrompt returns the prompt of the search, tagged with the
+// case mode and the regexp mode, unless they're the default ones.
+func (m Model) searchPrompt() string {
+	tags := ""
+	switch m.caseMode {
+	case CaseSensitive:
+		tags += "Aa"
+	case CaseInsensitive:
+		tags += "aa"
+	}
+	if m.Regexp() {
+		tags += ".*"
+	}
+	if tags == "" {
+		return searchPrompt
+	}
+	return "[" + tags + "]" + searchPrompt
+}
@@ -0,0 +1,101 @@
+package tree
+
+// more is the row rendered in place of the children beyond the shown chunks, see SetChunkSize.
+type more struct {
+	placeholder
+	shown int // the number of the children shown before the row
+}
+
+// Prefix keeps the names aligned with the ones of the real nodes.
+func (r *more) Prefix() string {
+	if r.parent == nil {
+		return ""
+	}
+	return r.placeholder.Prefix()
+}
+
+// WithChunkSize limits the number of the children of a node shown at once, see SetChunkSize.
+func WithChunkSize(n int) Option {
+	return func(m *Model) {
+		m.chunkSize = max(n, 0)
+	}
+}
+
+// SetChunkSize limits the number of the children of a node shown at once, e.g. for
+// directories with thousands of files. The rest of them are hidden behind a row which
+// shows the next chunk once activated, see ShowMore. Zero, the default, shows all of them.
+func (m *Model) SetChunkSize(n int) {
+	m.chunkSize = max(n, 0)
+	m.chunks = map[Node]*more{}
+	m.refreshAndSelect(m.currentNode())
+}
+
+// ShowMore shows the next chunk of the children hidden behind the row under the cursor,
+// the cursor stays on the same row, i.e. on the first of the shown children.
+// It reports whether the cursor was on such a row, see SetChunkSize.
+func (m *Model) ShowMore() bool {
+	row, ok := m.currentNode().(*more)
+	if !ok {
+		return false
+	}
+	row.shown += m.chunkSize
+	m.refreshAndSelect(nil)
+	return true
+}
+
+// moreRow returns the row showing more of the children of the parent, or of the top level nodes for nil.
+// The rows are reused between refreshes, so the number of the shown children is kept.
+func (m Model) moreRow(parent Node) *more {
+	row, ok := m.chunks[parent]
+	if !ok {
+		row = &more{placeholder: placeholder{parent: parent}, shown: m.chunkSize}
+		m.chunks[parent] = row
+	}
+	return row
+}
+
+// withChunks drops the children beyond the shown chunks of every node, along with their
+// descendants, inserting the row showing more of them in their place.
+func (m Model) withChunks(ns Nodes) Nodes {
+	if m.chunkSize == 0 {
+		return ns
+	}
+	res := make(Nodes, 0, len(ns))
+	counts := map[Node]int{}
+	for i := 0; i < len(ns); i++ {
+		parent := ns[i].Parent()
+		counts[parent]++
+		row := m.moreRow(parent)
+		if counts[parent] <= row.shown {
+			res = append(res, ns[i])
+			continue
+		}
+
+		// skipping the rest of the siblings, counting them along the way
+		hidden := 0
+		for ; i < len(ns) && (parent == nil || isDescendant(ns[i], parent)); i++ {
+			if ns[i].Parent() == parent {
+				hidden++
+			}
+		}
+		i--
+		row.name = m.tr(TextShowMore, min(m.chunkSize, hidden))
+		row.state = row.state&NodeSelected | NodeLastChild | NodeHasPreviousSibling
+		res = append(res, row)
+	}
+	return res
+}
+
+// showChunkOf shows enough chunks of the siblings of the node for it to be shown.
+func (m *Model) showChunkOf(n Node) {
+	if m.chunkSize == 0 {
+		return
+	}
+	siblings := m.tree
+	if p := n.Parent(); p != nil {
+		siblings = p.Children()
+	}
+	i := siblings.visible().sorted(m.less).index(n)
+	row := m.moreRow(n.Parent())
+	row.shown = max(row.shown, (i/m.chunkSize+1)*m.chunkSize)
+}
@@ -0,0 +1,60 @@
+package tree
+
+import (
+	"io"
+	"os"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ClipboardWriter writes the given text to the system clipboard.
+type ClipboardWriter func(text string) error
+
+// OSC52Clipboard writes to the clipboard of the terminal using the OSC52 escape sequence,
+// which works over SSH as well, as long as the terminal supports it.
+// The sequence goes to os.Stderr, straight from the goroutine running the command,
+// bypassing the program's renderer, so it's lost if stderr isn't the terminal.
+// Use OSC52ClipboardTo for the programs writing to a different output, see tea.WithOutput.
+func OSC52Clipboard(text string) error {
+	return OSC52ClipboardTo(os.Stderr)(text)
+}
+
+// OSC52ClipboardTo returns a ClipboardWriter writing the OSC52 escape sequence to w,
+// e.g. to the output of the program, see OSC52Clipboard.
+func OSC52ClipboardTo(w io.Writer) ClipboardWriter {
+	return func(text string) error {
+		seq := osc52.New(text)
+		if os.Getenv("TMUX") != "" {
+			seq = seq.Tmux()
+		}
+		_, err := seq.WriteTo(w)
+		return err
+	}
+}
+
+// CopiedMsg is emitted after an attempt to copy the path of a node to the clipboard.
+type CopiedMsg struct {
+	Node Node
+	Path string
+	Err  error
+}
+
+// CopyPath returns a command copying the path of the node pointed at by m.cursor to the clipboard.
+// The path consists of the names of all of the node's ancestors and the node itself,
+// joined with CopySeparator.
+func (m Model) CopyPath() tea.Cmd {
+	if len(m.nodes) == 0 {
+		return noop
+	}
+
+	n := m.currentNode()
+	path := joinPath(n, m.CopySeparator)
+	write := m.Clipboard
+	if write == nil {
+		write = OSC52Clipboard
+	}
+	return func() tea.Msg {
+		return CopiedMsg{Node: n, Path: path, Err: write(path)}
+	}
+}
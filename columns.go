@@ -0,0 +1,75 @@
+package tree
+
+import "strings"
+
+// WithAlignedPrefixes aligns the prefixes into columns, see SetAlignedPrefixes.
+func WithAlignedPrefixes() Option {
+	return func(m *Model) {
+		m.alignPrefixes = true
+	}
+}
+
+// SetAlignedPrefixes toggles aligning the prefixes of the nodes into columns,
+// so that prefixes like `-rwxr-xr-x 1000:1000 4.0K` of varying widths don't
+// produce ragged connectors. The prefixes are split into columns on whitespace,
+// every column is as wide as its widest value among the visible nodes,
+// unless the widths are given with SetPrefixColumns.
+func (m *Model) SetAlignedPrefixes(on bool) {
+	m.alignPrefixes = on
+	m.measurePrefixes()
+	m.rerender()
+}
+
+// SetPrefixColumns sets the widths of the columns the prefixes are aligned into,
+// instead of measuring them, which also turns the alignment on. No widths measure them again.
+// The values wider than their column are not truncated.
+func (m *Model) SetPrefixColumns(widths ...int) {
+	m.prefixWidths = widths
+	m.alignPrefixes = true
+	m.measurePrefixes()
+	m.rerender()
+}
+
+// measurePrefixes computes the widths of the columns of the prefixes of the visible nodes.
+func (m *Model) measurePrefixes() {
+	m.prefixColumns = nil
+	if !m.alignPrefixes {
+		return
+	}
+	if len(m.prefixWidths) > 0 {
+		m.prefixColumns = m.prefixWidths
+		return
+	}
+	columns := []int{}
+	for _, n := range m.nodes {
+		for i, field := range strings.Fields(n.Prefix()) {
+			if i == len(columns) {
+				columns = append(columns, 0)
+			}
+			columns[i] = max(columns[i], textWidth(field))
+		}
+	}
+	m.prefixColumns = columns
+}
+
+// alignPrefix pads every column of the prefix to the width of the column,
+// the prefix is returned as is if the alignment is off.
+func (m Model) alignPrefix(prefix string) string {
+	if len(m.prefixColumns) == 0 {
+		return prefix
+	}
+	fields := strings.Fields(prefix)
+	b := strings.Builder{}
+	for i, w := range m.prefixColumns {
+		field := ""
+		if i < len(fields) {
+			field = fields[i]
+		}
+		b.WriteString(field + strings.Repeat(" ", max(w-textWidth(field), 0)) + " ")
+	}
+	// the values not fitting into any column
+	for _, field := range fields[min(len(fields), len(m.prefixColumns)):] {
+		b.WriteString(field + " ")
+	}
+	return b.String()
+}
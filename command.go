@@ -0,0 +1,118 @@
+package tree
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// mode determines where the key events are routed to.
+type mode int
+
+const (
+	// modeNormal routes keys to the tree navigation
+	modeNormal mode = iota
+	// modeCommand routes keys to the command prompt
+	modeCommand
+	// modeRename routes keys to the editor of the node's name
+	modeRename
+	// modeSearch routes keys to the search prompt
+	modeSearch
+	// modeFilter routes keys to the filter bar
+	modeFilter
+)
+
+const commandPrompt = ":"
+
+func newPrompt() textinput.Model {
+	p := textinput.New()
+	p.Prompt = commandPrompt
+	return p
+}
+
+// OpenCommandPrompt opens the command prompt at the bottom of the tree.
+// Supported commands are:
+//
+//	select <glob>, check <glob>, mark <glob> - toggles the marked state of all visible nodes matching the glob
+//	<number> - moves the cursor to the given row, see GotoLine
+func (m *Model) OpenCommandPrompt() tea.Cmd {
+	m.mode = modeCommand
+	m.promptErr = nil
+	m.prompt.Prompt = commandPrompt
+	m.prompt.Reset()
+	m.layout()
+	return m.prompt.Focus()
+}
+
+// CloseCommandPrompt closes the command prompt, discarding its content.
+func (m *Model) CloseCommandPrompt() {
+	m.mode = modeNormal
+	m.promptErr = nil
+	m.prompt.Reset()
+	m.prompt.Blur()
+	m.layout()
+}
+
+func (m Model) updateCommand(msg tea.Msg) (Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.KeyMap.Cancel):
+			m.CloseCommandPrompt()
+			return m, noop
+		case key.Matches(msg, m.KeyMap.Confirm):
+			if err := m.runCommand(m.prompt.Value()); err != nil {
+				// leaving the prompt open so the command can be fixed
+				m.promptErr = err
+				return m, noop
+			}
+			m.CloseCommandPrompt()
+			return m, noop
+		}
+		m.promptErr = nil
+	}
+
+	var cmd tea.Cmd
+	m.prompt, cmd = m.prompt.Update(msg)
+	return m, cmd
+}
+
+// runCommand executes a single command entered into the command prompt.
+func (m *Model) runCommand(input string) error {
+	name, arg, _ := strings.Cut(strings.TrimSpace(input), " ")
+	arg = strings.TrimSpace(arg)
+	if line, err := strconv.Atoi(name); err == nil {
+		m.GotoLine(line)
+		return nil
+	}
+	switch name {
+	case "":
+		return nil
+	case "select", "check", "mark":
+		if arg == "" {
+			return errors.New(m.tr(TextMissingPattern, name))
+		}
+		if _, err := m.ToggleMarkMatching(arg); err != nil {
+			return errors.New(m.tr(TextInvalidPattern, arg))
+		}
+		return nil
+	}
+	return errors.New(m.tr(TextUnknownCommand, name))
+}
+
+// prompting reports whether the prompt line is shown.
+func (m Model) prompting() bool {
+	return m.mode == modeCommand || m.mode == modeSearch
+}
+
+// promptView renders the command prompt line, along with the error of the last command, if any.
+func (m Model) promptView() string {
+	if m.promptErr == nil {
+		return m.prompt.View()
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Left, m.prompt.View(), " ", m.promptErr.Error())
+}
@@ -0,0 +1,41 @@
+package tree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// NodeContextMsg is emitted when the user asks for the context menu of a node.
+type NodeContextMsg struct {
+	Node Node
+	// ScreenRow is the row of the node within the tree's View, so that the
+	// host can position the menu next to it.
+	ScreenRow int
+}
+
+// OpenContextMenu returns a command emitting a NodeContextMsg for the node pointed at by m.cursor.
+func (m Model) OpenContextMenu() tea.Cmd {
+	if len(m.nodes) == 0 {
+		return noop
+	}
+	row := m.rowOf(m.cursor)
+	if k := m.stickyRow(m.cursor); k != -1 {
+		row = m.view.YOffset + k
+	}
+	msg := NodeContextMsg{
+		Node:      m.currentNode(),
+		ScreenRow: m.screenRow(row),
+	}
+	return func() tea.Msg { return msg }
+}
+
+// screenRow maps the row of the viewport content to the row within the tree's View.
+func (m Model) screenRow(row int) int {
+	if m.headerShown() {
+		row++
+	}
+	if m.titleShown() {
+		row++
+	}
+	if m.filterBarShown() && m.filterBar == FilterBarTop {
+		row++
+	}
+	return m.pinnedRows() + row - m.view.YOffset
+}
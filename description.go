@@ -0,0 +1,29 @@
+package tree
+
+// Describer is an optional interface for nodes with a description, rendered
+// with the Description style on a second row under the name, like the
+// descriptions of the items of the list bubble.
+type Describer interface {
+	Description() string
+}
+
+// description returns the description of the node, empty if it has none.
+func description(n Node) string {
+	if d, ok := n.(Describer); ok {
+		return d.Description()
+	}
+	return ""
+}
+
+// renderDescription renders the description of the node clipped to the given width,
+// unless it's 0, or an empty string if it has none.
+func (m Model) renderDescription(n Node, width int) string {
+	d := description(n)
+	if d == "" || isPlaceholder(n) {
+		return ""
+	}
+	if width > 0 && textWidth(d) > width {
+		d = m.Truncation.shorten(d, width)
+	}
+	return m.Styles.Description.Copy().Inherit(m.nodeStyle(n)).Render(d)
+}
@@ -0,0 +1,156 @@
+package tree
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DefaultChangeHighlight is how long the changes made by UpdateNodes are highlighted for by default.
+const DefaultChangeHighlight = 2 * time.Second
+
+// change is the way a node has changed with UpdateNodes.
+type change int
+
+const (
+	changeAdded change = iota + 1
+	changeChanged
+)
+
+// changesSettledMsg ends the highlighting of the changes made by the UpdateNodes with the same id.
+type changesSettledMsg struct {
+	id int
+}
+
+// ghost is the row rendered in place of a node removed by UpdateNodes, until the changes settle.
+type ghost struct {
+	removed Node
+	parent  Node // the counterpart of the removed node's parent among the new nodes
+	after   Node // the counterpart of the removed node's previous sibling among the new nodes
+	state   NodeState
+}
+
+func (g *ghost) Name() string          { return g.removed.Name() }
+func (g *ghost) Prefix() string        { return g.removed.Prefix() }
+func (g *ghost) Parent() Node          { return g.parent }
+func (g *ghost) Children() Nodes       { return nil }
+func (g *ghost) State() NodeState      { return g.state }
+func (g *ghost) SetState(st NodeState) { g.state = st }
+
+// WithChangeHighlight sets how long the changes made by UpdateNodes are highlighted for,
+// zero turns the highlighting off.
+func WithChangeHighlight(d time.Duration) Option {
+	return func(m *Model) {
+		m.changeHighlight = d
+	}
+}
+
+// UpdateNodes replaces the nodes of the tree just like SetNodes does, highlighting the added
+// and the changed nodes, and keeping the removed ones in place, until the returned command
+// settles the changes, see WithChangeHighlight. The nodes are matched by their identity,
+// see Identifiable, and they've changed if their name or prefix has.
+func (m *Model) UpdateNodes(ns Nodes) tea.Cmd {
+	if m.changeHighlight <= 0 {
+		m.SetNodes(ns)
+		return noop
+	}
+
+	old := map[string]Node{}
+	for _, n := range m.tree.all() {
+		old[nodeID(n)] = n
+	}
+	updated := map[string]Node{}
+	m.changes = map[Node]change{}
+	for _, n := range ns.all() {
+		id := nodeID(n)
+		updated[id] = n
+		prev, ok := old[id]
+		switch {
+		case !ok:
+			m.changes[n] = changeAdded
+		case prev.Name() != n.Name() || prev.Prefix() != n.Prefix():
+			m.changes[n] = changeChanged
+		}
+	}
+
+	m.ghosts = nil
+	// the counterparts of the visited nodes, and of the last kept child of every node
+	counterparts, after := map[Node]Node{}, map[Node]Node{}
+	traverse(m.tree, 0, func(n Node, _ int, _ Nodes, _ int) Nodes {
+		if isNil(n) {
+			return nil
+		}
+		parent := n.Parent()
+		counterpart, ok := updated[nodeID(n)]
+		if !ok {
+			// the descendants are gone along with it
+			m.ghosts = append(m.ghosts, &ghost{removed: n, parent: counterparts[parent], after: after[parent]})
+			return nil
+		}
+		counterparts[n] = counterpart
+		after[parent] = counterpart
+		return n.Children()
+	})
+
+	m.SetNodes(ns)
+	m.changeID++
+	id := m.changeID
+	return tea.Tick(m.changeHighlight, func(time.Time) tea.Msg {
+		return changesSettledMsg{id: id}
+	})
+}
+
+// settleChanges stops highlighting the changes, dropping the removed nodes.
+func (m *Model) settleChanges(msg changesSettledMsg) {
+	if msg.id != m.changeID || (m.changes == nil && m.ghosts == nil) {
+		return
+	}
+	m.changes, m.ghosts = nil, nil
+	m.refreshAndSelect(m.currentNode())
+}
+
+// isGhost reports whether the row stands for a node removed by UpdateNodes.
+func isGhost(n Node) bool {
+	_, ok := n.(*ghost)
+	return ok
+}
+
+// withGhosts inserts the removed nodes after their previous siblings,
+// or right below their parents, as long as the parents are expanded.
+func (m Model) withGhosts(ns Nodes) Nodes {
+	for _, g := range m.ghosts {
+		i := 0 // the top level ghost without a previous sibling goes first
+		switch {
+		case g.after != nil:
+			i = ns.index(g.after)
+			if i == -1 {
+				continue
+			}
+			// skipping the descendants of the previous sibling
+			for i++; i < len(ns) && isDescendant(ns[i], g.after); i++ {
+			}
+		case g.parent != nil:
+			i = ns.index(g.parent)
+			if i == -1 || !isExpanded(g.parent) {
+				continue
+			}
+			i++
+		}
+		g.state = NodeNone
+		if i == len(ns) || ns[i].Parent() != g.parent {
+			g.state |= NodeLastChild
+		}
+		ns = append(ns[:i:i], append(Nodes{g}, ns[i:]...)...)
+	}
+	return ns
+}
+
+// isDescendant reports whether the node is below the ancestor.
+func isDescendant(n, ancestor Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package tree
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// WithEmptyMessage sets the message shown in place of the tree while it has no nodes to show, see SetEmptyMessage.
+func WithEmptyMessage(msg string) Option {
+	return func(m *Model) {
+		m.emptyMessage = msg
+	}
+}
+
+// SetEmptyMessage sets the message shown in place of the tree while it has no nodes to show,
+// e.g. "empty directory", or "no results" when everything is filtered out.
+// An empty message falls back to the TextNoNodes text.
+func (m *Model) SetEmptyMessage(msg string) {
+	m.emptyMessage = msg
+}
+
+// empty reports whether there are no nodes to show.
+func (m Model) empty() bool {
+	return len(m.nodes) == 0
+}
+
+// emptyView renders the message in place of the viewport, filling up its height
+// so the sections below it stay where they are.
+func (m Model) emptyView() string {
+	if m.view.Height <= 0 {
+		return ""
+	}
+	msg := m.emptyMessage
+	if msg == "" {
+		msg = m.tr(TextNoNodes)
+	}
+	rows := make([]string, m.view.Height)
+	rows[0] = m.Styles.EmptyTree.Render(m.fitWidth(msg))
+	style := lipgloss.NewStyle().Width(m.Width())
+	return style.Render(strings.Join(rows, "\n"))
+}
@@ -0,0 +1,80 @@
+package tree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// SelectionChangedMsg is emitted by Update when the cursor ends up on another node,
+// e.g. for loading a preview of the selected one.
+// The nodes are nil when the cursor is on a placeholder row, or the tree is empty.
+type SelectionChangedMsg struct {
+	Previous Node
+	Current  Node
+}
+
+// NodeExpandedMsg is emitted by Update when a node gets expanded, e.g. for fetching its children lazily.
+type NodeExpandedMsg struct {
+	Node Node
+}
+
+// NodeCollapsedMsg is emitted by Update when a node gets collapsed.
+type NodeCollapsedMsg struct {
+	Node Node
+}
+
+// setCollapsed sets the collapsed state of the node, recording the change
+// so that it can be emitted from Update.
+func (m *Model) setCollapsed(n Node, collapsed bool) {
+	if collapsed == !isExpanded(n) {
+		return
+	}
+	if collapsed {
+		n.SetState(n.State() | NodeCollapsed)
+		m.events = append(m.events, NodeCollapsedMsg{Node: n})
+	} else {
+		n.SetState(n.State() &^ NodeCollapsed)
+		m.retryLoad(n)
+		m.events = append(m.events, NodeExpandedMsg{Node: n})
+	}
+}
+
+// emitEvents returns a command emitting the recorded changes, along with the
+// SelectionChangedMsg if the selected node is no longer the previous one,
+// followed by the commands returned by the callbacks, e.g. OnSelect.
+func (m *Model) emitEvents(previous Node) tea.Cmd {
+	msgs := m.events
+	m.events = nil
+	if current := m.SelectedNode(); current != previous {
+		msgs = append(msgs, SelectionChangedMsg{Previous: previous, Current: current})
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	cmds := make([]tea.Cmd, 0, len(msgs))
+	hooks := []tea.Cmd{}
+	for _, msg := range msgs {
+		msg := msg
+		cmds = append(cmds, func() tea.Msg { return msg })
+		if hook := m.hook(msg); hook != nil {
+			hooks = append(hooks, hook)
+		}
+	}
+	return tea.Batch(tea.Sequence(cmds...), tea.Batch(hooks...))
+}
+
+// hook calls the callback registered for the message, if any.
+func (m Model) hook(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case SelectionChangedMsg:
+		if m.OnSelect != nil {
+			return m.OnSelect(msg.Current)
+		}
+	case NodeExpandedMsg:
+		if m.OnExpand != nil {
+			return m.OnExpand(msg.Node)
+		}
+	case NodeCollapsedMsg:
+		if m.OnCollapse != nil {
+			return m.OnCollapse(msg.Node)
+		}
+	}
+	return nil
+}
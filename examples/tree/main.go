@@ -45,34 +45,11 @@ func (n *node) SetState(st tree.NodeState) {
 	n.state = st
 }
 
-const (
-	Collapsed = ">"
-	Expanded  = "v"
-)
-
 func (n node) Name() string {
-	name := filepath.Base(n.path)
 	if n.parent == nil {
-		name = n.path
-	}
-
-	hints := n.state
-	annotation := ""
-	s := strings.Builder{}
-	if hints&tree.NodeCollapsible == tree.NodeCollapsible {
-		annotation = Expanded
-		if hints&tree.NodeCollapsed == tree.NodeCollapsed {
-			annotation = Collapsed
-		}
+		return n.path
 	}
-
-	if len(annotation) > 0 {
-		fmt.Fprintf(&s, "%-2s%s", annotation, name)
-	} else {
-		fmt.Fprintf(&s, "%s", name)
-	}
-
-	return s.String()
+	return filepath.Base(n.path)
 }
 
 func (n node) Children() tree.Nodes {
@@ -83,6 +60,10 @@ func (n node) Children() tree.Nodes {
 	return nodes
 }
 
+func (n *node) SwapChildren(i, j int) {
+	n.children[i], n.children[j] = n.children[j], n.children[i]
+}
+
 func (n node) State() tree.NodeState {
 	return n.state
 }
@@ -135,7 +116,6 @@ func buildNodeTree(root string, maxDepth int) tree.Nodes {
 			allNodes = append(allNodes, node)
 		} else {
 			node.parent = parent
-			node.state |= tree.NodeCollapsed
 			parent.children = append(parent.children, node)
 		}
 		return nil
@@ -215,7 +195,7 @@ func main() {
 		path = abs
 	}
 
-	t := tree.New(buildNodeTree(path, depth))
+	t := tree.New(buildNodeTree(path, depth), tree.WithExpansion(tree.ExpandRoots))
 	t.Symbols = symbols
 	m := quittingTree{Model: t}
 
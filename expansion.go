@@ -0,0 +1,77 @@
+package tree
+
+// ExpansionPolicy decides whether a collapsible node at the given depth
+// should be expanded. Top level nodes are at depth 0.
+type ExpansionPolicy func(n Node, depth int) bool
+
+// ExpandAll expands every collapsible node.
+func ExpandAll(Node, int) bool {
+	return true
+}
+
+// CollapseAll collapses every collapsible node.
+func CollapseAll(Node, int) bool {
+	return false
+}
+
+// ExpandRoots expands only the top level nodes, showing their children collapsed.
+func ExpandRoots(_ Node, depth int) bool {
+	return depth == 0
+}
+
+// ExpandToDepth expands the nodes up to the given depth, so that the nodes at
+// depth d are still visible, but collapsed.
+func ExpandToDepth(d int) ExpansionPolicy {
+	return func(_ Node, depth int) bool {
+		return depth < d
+	}
+}
+
+// WithExpansion sets the policy used for the initial expansion of the nodes.
+// Without it, the nodes are shown expanded, unless the nodes themselves
+// report being collapsed.
+func WithExpansion(p ExpansionPolicy) Option {
+	return func(m *Model) {
+		m.expansion = p
+	}
+}
+
+// WithStartCollapsed shows only the top level nodes and their children, collapsed,
+// like the file browsers do for large directories. It's the same as WithExpansion(ExpandRoots).
+func WithStartCollapsed() Option {
+	return WithExpansion(ExpandRoots)
+}
+
+// ApplyExpansion expands and collapses all of the nodes according to the policy.
+// The cursor stays on the same node if it is still visible.
+func (m *Model) ApplyExpansion(p ExpansionPolicy) {
+	if p == nil {
+		return
+	}
+	m.saveUndo()
+	m.saveExpansion()
+	current := m.currentNode()
+	m.tree.expand(p)
+	m.refreshAndSelect(current)
+}
+
+// expand sets the collapsed state of every collapsible node according to the
+// policy, nil policy leaves the nodes as they are.
+func (ns Nodes) expand(p ExpansionPolicy) {
+	if p == nil {
+		return
+	}
+	for _, top := range ns {
+		// the nodes might not be top level ones, e.g. when inserted with InsertNode
+		Nodes{top}.walk(getDepth(top), func(n Node, depth int) {
+			if !hasChildren(n) && !isCollapsible(n) {
+				return
+			}
+			if p(n, depth) {
+				n.SetState(n.State() &^ NodeCollapsed)
+			} else {
+				n.SetState(n.State() | NodeCollapsed)
+			}
+		})
+	}
+}
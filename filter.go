@@ -0,0 +1,115 @@
+package tree
+
+// SetFilter hides every node for which keep returns false, unless it is an
+// ancestor of a node which is kept, so the structure of the tree stays readable.
+// The ancestors of the kept nodes are expanded.
+// Nodes which were already hidden, e.g. by the host, stay hidden along with their descendants.
+func (m *Model) SetFilter(keep func(Node) bool) {
+	m.saveUndo()
+	m.saveExpansion()
+	m.setFilter(keep, "")
+}
+
+// SetFilterQuery keeps only the nodes matching the query, and
+// their ancestors, highlighting the matching parts of the names.
+// The nodes are matched using the model's Matcher, see SetMatcher and SetSearchTarget.
+// Empty query clears the filter.
+func (m *Model) SetFilterQuery(query string) {
+	if query == "" {
+		m.ClearFilter()
+		return
+	}
+	m.saveUndo()
+	m.saveExpansion()
+	m.setFilter(m.queryFilter(query), query)
+}
+
+// FilterQuery returns the query set by SetFilterQuery, empty if there is none.
+func (m Model) FilterQuery() string {
+	return m.filterQuery
+}
+
+// ClearFilter shows all of the nodes hidden by the filter.
+func (m *Model) ClearFilter() {
+	if m.filter == nil {
+		return
+	}
+	m.saveUndo()
+	m.clearFilter()
+}
+
+// setFilter replaces the filter, without recording it for undo.
+func (m *Model) setFilter(keep func(Node) bool, query string) {
+	current := m.currentNode()
+	m.unfilter()
+	m.filter = keep
+	m.filterQuery = query
+	m.applyFilter()
+	m.refreshAndSelect(current)
+}
+
+// clearFilter drops the filter, without recording it for undo.
+func (m *Model) clearFilter() {
+	if m.filter == nil {
+		return
+	}
+	current := m.currentNode()
+	m.unfilter()
+	m.filter = nil
+	m.filterQuery = ""
+	m.refreshAndSelect(current)
+}
+
+// queryFilter returns a filter keeping the nodes whose name matches the query.
+func (m Model) queryFilter(query string) func(Node) bool {
+	match := m.matchNode
+	return func(n Node) bool {
+		_, ok := match(query, n)
+		return ok
+	}
+}
+
+// Filtered reports whether a filter is active.
+func (m Model) Filtered() bool {
+	return m.filter != nil
+}
+
+// applyFilter hides the nodes not passing the filter, remembering them so they can be shown again.
+func (m *Model) applyFilter() {
+	if m.filter == nil {
+		return
+	}
+
+	order := Nodes{}
+	traverse(m.tree.visible(), 0, func(n Node, _ int, _ Nodes, _ int) Nodes {
+		order = append(order, n)
+		return n.Children().visible()
+	})
+	// whether the node, or any of its descendants, is kept; the descendants
+	// come after their ancestors, so they're done by the time the ancestors are
+	kept := map[Node]bool{}
+	for i := len(order) - 1; i >= 0; i-- {
+		n := order[i]
+		keptBelow := false
+		for _, child := range n.Children() {
+			keptBelow = keptBelow || kept[child]
+		}
+		if keptBelow {
+			m.setCollapsed(n, false)
+		}
+		if keptBelow || m.filter(n) {
+			kept[n] = true
+			continue
+		}
+		n.SetState(n.State() | NodeHidden)
+		m.filtered = append(m.filtered, n)
+	}
+}
+
+// unfilter shows the nodes hidden by the filter.
+func (m *Model) unfilter() {
+	for _, n := range m.filtered {
+		n.SetState(n.State() &^ NodeHidden)
+	}
+	m.filtered = nil
+}
@@ -0,0 +1,168 @@
+package tree
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FilterBarPosition places the built-in filter bar.
+type FilterBarPosition int
+
+const (
+	// FilterBarNone disables the filter bar
+	FilterBarNone FilterBarPosition = iota
+	// FilterBarTop shows the filter bar above the tree
+	FilterBarTop
+	// FilterBarBottom shows the filter bar below the tree
+	FilterBarBottom
+)
+
+// WithFilterBar enables the built-in filter bar at the given position.
+func WithFilterBar(pos FilterBarPosition) Option {
+	return func(m *Model) {
+		m.SetFilterBar(pos)
+	}
+}
+
+// SetFilterBar enables the built-in filter bar at the given position, or
+// disables it with FilterBarNone. The bar is shown while it is being edited,
+// or while the filter it has set is active.
+func (m *Model) SetFilterBar(pos FilterBarPosition) {
+	m.filterBar = pos
+	m.layout()
+}
+
+// OpenFilterBar focuses the filter bar. The tree is filtered while typing,
+// Enter keeps the filter and Esc clears it.
+func (m *Model) OpenFilterBar() tea.Cmd {
+	if m.filterBar == FilterBarNone {
+		return noop
+	}
+	m.mode = modeFilter
+	m.filterInput.Prompt = m.tr(TextFilterPrompt)
+	m.filterInput.SetValue(m.filterQuery)
+	m.filterInput.CursorEnd()
+	m.queries.rewind()
+	// the whole editing session is undone at once
+	m.saveUndo()
+	m.saveExpansion()
+	m.layout()
+	return m.filterInput.Focus()
+}
+
+// closeFilterBar stops editing the filter bar, keeping the filter.
+func (m *Model) closeFilterBar() {
+	m.mode = modeNormal
+	m.promptErr = nil
+	m.filterInput.Blur()
+	m.layout()
+}
+
+func (m Model) updateFilterBar(msg tea.Msg) (Model, tea.Cmd) {
+	if msg, ok := msg.(filterDebouncedMsg); ok {
+		if msg.seq == m.filterSeq {
+			m.flushFilter()
+		}
+		return m, noop
+	}
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.KeyMap.Cancel):
+			m.filterSeq++
+			m.filterPending = false
+			m.filterInput.Reset()
+			m.clearFilter()
+			m.closeFilterBar()
+			return m, noop
+		case key.Matches(msg, m.KeyMap.Confirm):
+			m.flushFilter()
+			m.queries.add(m.filterInput.Value())
+			m.closeFilterBar()
+			return m, noop
+		case key.Matches(msg, m.KeyMap.PrevQuery), key.Matches(msg, m.KeyMap.NextQuery):
+			q, ok := m.queries.recall(key.Matches(msg, m.KeyMap.NextQuery), m.filterInput.Value())
+			if !ok {
+				return m, noop
+			}
+			m.filterInput.SetValue(q)
+			m.filterInput.CursorEnd()
+			return m, m.filterTyped(q)
+		case key.Matches(msg, m.KeyMap.ToggleRegexp):
+			m.ToggleRegexp()
+			return m, m.filterTyped(m.filterInput.Value())
+		}
+	}
+
+	var cmd tea.Cmd
+	query := m.filterInput.Value()
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	if q := m.filterInput.Value(); q != query {
+		return m, tea.Batch(cmd, m.filterTyped(q))
+	}
+	return m, cmd
+}
+
+// filterDebouncedMsg is sent once the typing has paused for FilterDebounce,
+// the older ones are ignored.
+type filterDebouncedMsg struct {
+	seq int
+}
+
+// filterTyped applies the query right away, or once the typing pauses if FilterDebounce is set.
+func (m *Model) filterTyped(query string) tea.Cmd {
+	if m.FilterDebounce <= 0 {
+		m.filterLive(query)
+		return noop
+	}
+	m.filterSeq++
+	m.filterPending = true
+	seq := m.filterSeq
+	return tea.Tick(m.FilterDebounce, func(time.Time) tea.Msg {
+		return filterDebouncedMsg{seq: seq}
+	})
+}
+
+// flushFilter applies the query whose filtering has been postponed, if any.
+func (m *Model) flushFilter() {
+	if !m.filterPending {
+		return
+	}
+	// the ticks already on their way are stale now
+	m.filterSeq++
+	m.filterPending = false
+	m.filterLive(m.filterInput.Value())
+}
+
+// filterLive applies the query typed into the filter bar.
+// Malformed queries are reported in the bar, keeping the last valid filter.
+func (m *Model) filterLive(query string) {
+	if m.promptErr = m.validateQuery(query); m.promptErr != nil {
+		return
+	}
+	if query == "" {
+		m.clearFilter()
+		return
+	}
+	m.setFilter(m.queryFilter(query), query)
+}
+
+// filterBarShown reports whether the filter bar takes up a row.
+func (m Model) filterBarShown() bool {
+	return m.filterBar != FilterBarNone && (m.mode == modeFilter || m.filterQuery != "")
+}
+
+func (m Model) filterBarView() string {
+	if m.mode == modeFilter && m.promptErr != nil {
+		return lipgloss.JoinHorizontal(lipgloss.Left, m.filterInput.View(), " ", m.promptErr.Error())
+	}
+	if m.mode == modeFilter && m.filterPending {
+		return lipgloss.JoinHorizontal(lipgloss.Left, m.filterInput.View(), " ", m.Styles.FilterBar.Render(m.tr(TextFiltering)))
+	}
+	if m.mode == modeFilter {
+		return m.filterInput.View()
+	}
+	return m.Styles.FilterBar.Render(m.tr(TextFilterPrompt) + m.filterQuery)
+}
@@ -0,0 +1,86 @@
+package tree
+
+import (
+	"path"
+	"strings"
+)
+
+// PathSeparator is used for joining node names into a path.
+const PathSeparator = "/"
+
+// nodePath returns the names of all the ancestors of the given node, starting
+// from the top level one, and the node itself joined with PathSeparator.
+func nodePath(n Node) string {
+	return joinPath(n, PathSeparator)
+}
+
+// joinPath is the same as nodePath, but with a custom separator.
+func joinPath(n Node, sep string) string {
+	names := []string{}
+	for ; n != nil; n = n.Parent() {
+		names = append([]string{n.Name()}, names...)
+	}
+	return strings.Join(names, sep)
+}
+
+// relativePath is the same as nodePath, but without the top level node.
+func relativePath(n Node) string {
+	names := []string{}
+	for ; n != nil && n.Parent() != nil; n = n.Parent() {
+		names = append([]string{n.Name()}, names...)
+	}
+	return strings.Join(names, PathSeparator)
+}
+
+// validateGlob returns path.ErrBadPattern if any of the segments of the pattern is malformed.
+func validateGlob(pattern string) error {
+	for _, seg := range strings.Split(pattern, PathSeparator) {
+		if _, err := path.Match(seg, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchGlob reports whether the node matches the glob pattern.
+// Patterns without a separator are matched against the name of the node,
+// e.g. "*.log", everything else is matched against the path relative to the
+// top level node, where `**` matches any number of segments, e.g. "internal/**".
+// The escape sequences of the names are ignored.
+func matchGlob(pattern string, n Node) bool {
+	if !strings.Contains(pattern, PathSeparator) {
+		ok, _ := path.Match(pattern, stripANSI(n.Name()))
+		return ok
+	}
+	rel := stripANSI(relativePath(n))
+	if rel == "" {
+		return false
+	}
+	return matchSegments(
+		strings.Split(strings.Trim(pattern, PathSeparator), PathSeparator),
+		strings.Split(rel, PathSeparator),
+	)
+}
+
+// matchSegments matches the path segments one by one.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		// `**` swallows zero or more segments
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
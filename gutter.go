@@ -0,0 +1,87 @@
+package tree
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// LineNumbers determines what the gutter left of the tree shows.
+type LineNumbers int
+
+const (
+	// LineNumbersNone hides the gutter
+	LineNumbersNone LineNumbers = iota
+	// LineNumbersAbsolute shows the number of every row, counting from 1, see GotoLine
+	LineNumbersAbsolute
+	// LineNumbersRelative shows the distance of every row from the cursor, like vim's relativenumber,
+	// the row under the cursor shows its absolute number
+	LineNumbersRelative
+)
+
+// WithLineNumbers shows the line-number gutter, see SetLineNumbers.
+func WithLineNumbers(ln LineNumbers) Option {
+	return func(m *Model) {
+		m.lineNumbers = ln
+	}
+}
+
+// SetLineNumbers sets what the gutter left of the tree shows.
+func (m *Model) SetLineNumbers(ln LineNumbers) {
+	m.lineNumbers = ln
+	// the width of the rows changes
+	m.rerender()
+}
+
+// LineNumbers returns what the gutter left of the tree shows.
+func (m Model) LineNumbers() LineNumbers {
+	return m.lineNumbers
+}
+
+// cycleLineNumbers switches to the next mode of the gutter, wrapping around.
+func (m *Model) cycleLineNumbers() {
+	m.SetLineNumbers((m.lineNumbers + 1) % (LineNumbersRelative + 1))
+}
+
+// gutterWidth returns the width of the gutter, including the space separating it from the tree.
+func (m Model) gutterWidth() int {
+	if m.lineNumbers == LineNumbersNone {
+		return 0
+	}
+	return len(strconv.Itoa(max(len(m.nodes), 1))) + 1
+}
+
+// gutterView renders the line numbers of the rows currently in the viewport.
+func (m Model) gutterView() string {
+	w := m.gutterWidth() - 1
+	rows := make([]string, 0, m.view.Height)
+	stuck := m.sticky()
+	for row := m.view.YOffset; row < m.view.YOffset+m.view.Height; row++ {
+		i, first := m.nodeAtRow(row), row
+		if k := row - m.view.YOffset; k < len(stuck) {
+			// the rows covered by the sticky ancestors show their numbers
+			i, first = stuck[k], m.rowOf(stuck[k])
+		}
+		if i < 0 || i >= len(m.nodes) || m.rowOf(i) != first {
+			// past the end of the tree, or a continuation row of a node
+			rows = append(rows, strings.Repeat(" ", w+1))
+			continue
+		}
+		number, style := i+1, m.Styles.LineNumber
+		if i == m.cursor {
+			style = m.Styles.CurrentLineNumber
+		} else if m.lineNumbers == LineNumbersRelative {
+			number = abs(i - m.cursor)
+		}
+		rows = append(rows, style.Copy().Width(w).Align(lipgloss.Right).Render(strconv.Itoa(number))+" ")
+	}
+	return strings.Join(rows, "\n")
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,70 @@
+package tree
+
+// SetHiddenPredicate hides every node for which hide returns true, along with
+// its descendants, e.g. the dotfiles. The hidden nodes can be shown and hidden
+// again with ToggleHidden. Nil predicate shows all of them.
+func (m *Model) SetHiddenPredicate(hide func(Node) bool) {
+	m.hide = hide
+	m.showHidden = false
+	m.rehide()
+}
+
+// ToggleHidden shows the nodes hidden by the predicate set with SetHiddenPredicate,
+// or hides them again.
+func (m *Model) ToggleHidden() {
+	if m.hide == nil {
+		return
+	}
+	m.showHidden = !m.showHidden
+	m.rehide()
+}
+
+// ShowingHidden reports whether the nodes hidden by the predicate are currently shown.
+func (m Model) ShowingHidden() bool {
+	return m.showHidden
+}
+
+// rehide re-applies the predicate and the filter on top of it, keeping the
+// cursor on the same node, or on its closest visible ancestor.
+func (m *Model) rehide() {
+	current := m.selectedNode()
+	m.unfilter()
+	for _, n := range m.hidden {
+		n.SetState(n.State() &^ NodeHidden)
+	}
+	m.hidden = nil
+	m.hideNodes()
+	m.applyFilter()
+
+	visible := m.flattenNodes()
+	target := current
+	for n := current; n != nil; n = n.Parent() {
+		if visible.index(n) != -1 {
+			target = n
+			break
+		}
+	}
+	m.refreshAndSelect(target)
+}
+
+// hideNodes hides the nodes matching the predicate, unless they're being shown.
+func (m *Model) hideNodes() {
+	if m.hide == nil || m.showHidden {
+		return
+	}
+	for _, n := range m.tree.all() {
+		if !isHidden(n) && m.hide(n) {
+			n.SetState(n.State() | NodeHidden)
+			m.hidden = append(m.hidden, n)
+		}
+	}
+}
+
+// hiddenByPredicate returns the set of the nodes currently hidden by the predicate.
+func (m Model) hiddenByPredicate() map[Node]bool {
+	res := make(map[Node]bool, len(m.hidden))
+	for _, n := range m.hidden {
+		res[n] = true
+	}
+	return res
+}
@@ -0,0 +1,93 @@
+package tree
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// span is a [start, end) range of bytes within a string.
+type span struct {
+	start, end int
+}
+
+// highlightQuery returns the query whose matches should be highlighted,
+// the search one takes precedence over the filter one.
+func (m Model) highlightQuery() string {
+	if m.search.query != "" {
+		return m.search.query
+	}
+	return m.filterQuery
+}
+
+// substringSpans returns all non-overlapping occurrences of the query in s.
+func substringSpans(s, query string) []span {
+	if query == "" {
+		return nil
+	}
+	spans := []span{}
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], query)
+		if i == -1 {
+			return spans
+		}
+		start := offset + i
+		offset = start + len(query)
+		spans = append(spans, span{start: start, end: offset})
+	}
+}
+
+// renderName renders the (already truncated) name of the node within the
+// given width, highlighting the parts of it matching the active query.
+func (m Model) renderName(n Node, name string, width int) string {
+	style := m.nodeStyle(n)
+	align := m.alignment(n).Name
+	base := style.Copy().UnsetWidth().UnsetMaxWidth().UnsetAlign()
+	// the matches are found in the plain text, the highlighting replaces the colors of the name
+	plain := stripANSI(name)
+	match, _ := m.match(m.highlightQuery(), plain)
+
+	var content string
+	switch spans := match.spans(plain); {
+	case len(spans) > 0:
+		content = m.highlightSpans(plain, spans, base)
+	case lipgloss.Width(name) != textWidth(name):
+		// lipgloss counts the hyperlinks as text, so it would wrap the name padded by it
+		content = base.Render(name)
+	default:
+		return style.Copy().Width(width).MaxWidth(width).Align(align).Render(name)
+	}
+
+	padding := max(width-textWidth(content), 0)
+	left := int(float64(padding) * float64(align))
+	return pad(base, left) + content + pad(base, padding-left)
+}
+
+// highlightSpans renders the text with the spans highlighted on top of the base style.
+func (m Model) highlightSpans(text string, spans []span, base lipgloss.Style) string {
+	// every segment is styled on its own, so that the reset at the end of a
+	// highlighted segment doesn't leave the rest of the row unstyled
+	highlight := m.Styles.MatchHighlight.Copy().Inherit(base)
+
+	b := strings.Builder{}
+	last := 0
+	for _, s := range spans {
+		if s.start > last {
+			b.WriteString(base.Render(text[last:s.start]))
+		}
+		b.WriteString(highlight.Render(text[s.start:s.end]))
+		last = s.end
+	}
+	if last < len(text) {
+		b.WriteString(base.Render(text[last:]))
+	}
+	return b.String()
+}
+
+// pad renders n spaces in the given style.
+func pad(style lipgloss.Style, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return style.Render(strings.Repeat(" ", n))
+}
@@ -0,0 +1,60 @@
+package tree
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// DefaultHistorySize is the default maximum number of visited nodes kept in the history
+	DefaultHistorySize = 100
+	// DefaultHistoryDwell is the default time the cursor has to rest on a node for it to count as visited
+	DefaultHistoryDwell = 500 * time.Millisecond
+)
+
+// History returns the nodes the cursor has rested on, from the oldest to the most recent one.
+func (m Model) History() Nodes {
+	return m.history
+}
+
+// HistoryBack moves the cursor back to the most recently visited node which is
+// still visible, dropping it from the history.
+func (m *Model) HistoryBack() tea.Cmd {
+	for len(m.history) > 0 {
+		n := m.history[len(m.history)-1]
+		m.history = m.history[:len(m.history)-1]
+
+		i := m.nodes.index(n)
+		if i == -1 || i == m.cursor {
+			continue
+		}
+
+		m.saveUndo()
+		// jumping back shouldn't record the node we're leaving,
+		// otherwise going back twice would return us here
+		m.arrived = time.Time{}
+		cmd := m.setCursor(i)
+		m.scrollToCursor()
+		return cmd
+	}
+	return noop
+}
+
+// recordVisit adds the node the cursor is leaving to the history, if the cursor rested on it long enough.
+func (m *Model) recordVisit(n Node) {
+	arrived := m.arrived
+	m.arrived = time.Now()
+
+	if arrived.IsZero() || m.HistorySize <= 0 || time.Since(arrived) < m.HistoryDwell {
+		return
+	}
+	if len(m.history) > 0 && m.history[len(m.history)-1] == n {
+		return
+	}
+
+	m.history = append(m.history, n)
+	if overflow := len(m.history) - m.HistorySize; overflow > 0 {
+		m.history = m.history[overflow:]
+	}
+}
@@ -0,0 +1,57 @@
+package tree
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Hydrator is an optional interface for nodes whose metadata is expensive to
+// compute, e.g. it requires stat calls or API lookups.
+// Instead of computing it while the tree is being built, Hydrate is called
+// lazily, only once the node enters the viewport for the first time.
+type Hydrator interface {
+	// Hydrate should return a command fetching the metadata of the node, which
+	// in turn returns a HydratedMsg.
+	Hydrate() tea.Cmd
+}
+
+// HydratedMsg should be returned by the command from Hydrate, once the
+// metadata of the node has been fetched.
+type HydratedMsg struct {
+	Node Node
+	// Apply, if set, is called from Update so the node can be modified
+	// without racing with the rendering.
+	Apply func()
+}
+
+// hydrateVisible dispatches the Hydrate commands of all the nodes in the
+// viewport which haven't been hydrated yet, along with the LoadChildren
+// commands of the expanded nodes whose loading rows are in the viewport.
+func (m *Model) hydrateVisible() tea.Cmd {
+	if m.view.Height == 0 || len(m.nodes) == 0 {
+		return noop
+	}
+
+	cmds := []tea.Cmd{}
+	top, bottom := m.visibleRows()
+	for i := m.nodeAtRow(top); i <= m.nodeAtRow(bottom) && i < len(m.nodes); i++ {
+		n := m.nodes[i]
+		if load := loadChildren(n); load != nil {
+			cmds = append(cmds, load)
+		}
+		h, ok := n.(Hydrator)
+		if !ok || n.State().Is(NodeHydrated) {
+			continue
+		}
+		n.SetState(n.State() | NodeHydrated)
+		cmds = append(cmds, h.Hydrate())
+	}
+	return tea.Batch(cmds...)
+}
+
+// applyHydration applies the result of a Hydrate command and re-renders the node.
+func (m *Model) applyHydration(msg HydratedMsg) {
+	if msg.Apply != nil {
+		msg.Apply()
+	}
+	m.rerenderNode(m.nodes.index(msg.Node))
+}
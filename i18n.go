@@ -0,0 +1,94 @@
+package tree
+
+import "fmt"
+
+// Translator returns the text shown to the user for the built-in string with
+// the given key, formatted with the given arguments.
+type Translator func(key string, args ...any) string
+
+// Keys of the built-in strings passed to the Translator.
+const (
+	// TextUnknownCommand takes the name of the command
+	TextUnknownCommand = "unknown_command"
+	// TextMissingPattern takes the name of the command
+	TextMissingPattern = "missing_pattern"
+	// TextInvalidPattern takes the pattern
+	TextInvalidPattern = "invalid_pattern"
+	// TextFilterPrompt is the prompt of the filter bar
+	TextFilterPrompt = "filter_prompt"
+	// TextFiltering is shown in the filter bar while the filtering is postponed, see FilterDebounce
+	TextFiltering = "filtering"
+	// TextMatchPosition takes the position of the cursor among the matches, and their count
+	TextMatchPosition = "match_position"
+	// TextMatchCount takes the count of the matches, it's shown while the cursor isn't on one of them
+	TextMatchCount = "match_count"
+	// TextNoMatches is shown when nothing matches the search query
+	TextNoMatches = "no_matches"
+	// TextNoNodes is shown in place of the tree while it has no nodes to show, see SetEmptyMessage
+	TextNoNodes = "no_nodes"
+	// TextLoading is shown next to the spinner while the tree is loading, see SetLoading
+	TextLoading = "loading"
+	// TextLoadingChildren is shown below an expanded node while its children are being loaded, see Loader
+	TextLoadingChildren = "loading_children"
+	// TextShowMore takes the number of the children shown by activating the row, see SetChunkSize
+	TextShowMore = "show_more"
+	// TextBrokenNode is shown in place of the nodes which couldn't be rendered, see Model.Err
+	TextBrokenNode = "broken_node"
+	// TextEmpty is shown below an expanded node without children, no row is shown if it's empty
+	TextEmpty = "empty"
+	// TextChildCount takes the number of the children of a collapsed node, see SetChildCount,
+	// no badge is shown if it's empty
+	TextChildCount = "child_count"
+	// TextItemCount takes the number of the visible nodes, see Summary
+	TextItemCount = "item_count"
+	// TextScrollPercent takes how far the tree is scrolled, in percent, see Summary
+	TextScrollPercent = "scroll_percent"
+	// TextFilterSummary takes the filter query, see Summary
+	TextFilterSummary = "filter_summary"
+)
+
+// defaultTexts are the English texts of the built-in strings, used as fmt format strings.
+var defaultTexts = map[string]string{
+	TextUnknownCommand:  "unknown command: %s",
+	TextMissingPattern:  "%s: missing pattern",
+	TextInvalidPattern:  "invalid pattern: %s",
+	TextFilterPrompt:    "Filter: ",
+	TextFiltering:       "filtering…",
+	TextMatchPosition:   "match %d/%d",
+	TextMatchCount:      "%d matches",
+	TextNoMatches:       "no matches",
+	TextNoNodes:         "no results",
+	TextLoading:         "loading…",
+	TextLoadingChildren: "loading…",
+	TextShowMore:        "… load %d more",
+	TextBrokenNode:      "⚠ broken node",
+	TextEmpty:           "(empty)",
+	TextChildCount:      "(%d)",
+	TextItemCount:       "%d items",
+	TextScrollPercent:   "%d%%",
+	TextFilterSummary:   "filter: %s",
+}
+
+// DefaultTranslator returns the English text for the key, or the key itself if it's unknown.
+func DefaultTranslator(key string, args ...any) string {
+	text, ok := defaultTexts[key]
+	if !ok {
+		return key
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// SetTranslator sets the function used for all of the user-visible built-in
+// strings, so they can be localized. Setting nil restores DefaultTranslator.
+func (m *Model) SetTranslator(t Translator) {
+	m.translator = t
+	m.refresh()
+}
+
+// tr returns the text for the built-in string with the given key.
+func (m Model) tr(key string, args ...any) string {
+	if m.translator == nil {
+		return DefaultTranslator(key, args...)
+	}
+	return m.translator(key, args...)
+}
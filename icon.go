@@ -0,0 +1,25 @@
+package tree
+
+// NodeIcon is an optional interface for nodes with an icon, e.g. a nerd-font
+// file type glyph, rendered between the tree symbols and the name with the Icon style.
+type NodeIcon interface {
+	Icon() string
+}
+
+// renderIcon returns the styled icon of the node followed by a space, empty if it has none.
+func (m Model) renderIcon(n Node) string {
+	i, ok := n.(NodeIcon)
+	if !ok || i.Icon() == "" {
+		return ""
+	}
+	return m.Styles.Icon.Render(i.Icon()) + " "
+}
+
+// fitDecorations returns the decorations of the name, e.g. the icon, if they
+// leave enough room for the name, and the width left for the name.
+func fitDecorations(decorations string, nameWidth int) (string, int) {
+	if w := textWidth(decorations); nameWidth-w >= minNameWidth {
+		return decorations, nameWidth - w
+	}
+	return "", nameWidth
+}
@@ -0,0 +1,53 @@
+package tree
+
+import "github.com/charmbracelet/lipgloss"
+
+// Indicators are the glyphs rendered in front of the names of the collapsible
+// nodes, the leaves get the same amount of padding so the names stay aligned.
+type Indicators struct {
+	Expanded  string
+	Collapsed string
+	Placement IndicatorPlacement
+}
+
+// IndicatorPlacement determines where the indicators are rendered.
+type IndicatorPlacement int
+
+const (
+	// IndicatorBeforeName renders the indicators between the tree symbols and the name
+	IndicatorBeforeName IndicatorPlacement = iota
+	// IndicatorBeforePrefix renders the indicators at the very start of the row, before the Prefix
+	IndicatorBeforePrefix
+	// IndicatorNone doesn't render the indicators at all
+	IndicatorNone
+)
+
+// DefaultIndicators returns the default expand and collapse indicators.
+func DefaultIndicators() Indicators {
+	return Indicators{
+		Expanded:  "▾",
+		Collapsed: "▸",
+	}
+}
+
+// width returns the width of the widest indicator.
+func (i Indicators) width() int {
+	return max(lipgloss.Width(i.Expanded), lipgloss.Width(i.Collapsed))
+}
+
+// renderIndicator returns the styled indicator of the node followed by a space,
+// empty if the indicators are empty.
+func (m Model) renderIndicator(n Node) string {
+	w := m.Indicators.width()
+	if w == 0 {
+		return ""
+	}
+	glyph := ""
+	if isCollapsible(n) {
+		glyph = m.Indicators.Collapsed
+		if isExpanded(n) {
+			glyph = m.Indicators.Expanded
+		}
+	}
+	return m.Styles.Indicator.Copy().Width(w).Render(glyph) + " "
+}
@@ -0,0 +1,71 @@
+package tree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// Loader is an optional interface for nodes whose children are expensive to
+// fetch, e.g. directories of huge filesystems, or remote resources.
+// A collapsible Loader node without children is considered not to be loaded
+// yet, and once it's expanded a loading row is shown in place of its children
+// and LoadChildren is called. The node should implement ChildSetter, so the
+// loaded children can be spliced in.
+type Loader interface {
+	// LoadChildren should return a command fetching the children of the node,
+	// which in turn returns a ChildrenLoadedMsg.
+	LoadChildren() tea.Cmd
+}
+
+// ChildrenLoadedMsg should be returned by the command from LoadChildren, once
+// the children of the node have been fetched. Their Parent() should already return the node.
+type ChildrenLoadedMsg struct {
+	Node     Node
+	Children Nodes
+	// Err, if set, marks the node with the error, see SetNodeError.
+	// Expanding the node again tries loading the children again.
+	Err error
+}
+
+// retryLoad clears the error of the node whose children failed to load, so they're loaded again.
+func (m *Model) retryLoad(n Node) {
+	if unloaded(n) && failed(n) {
+		delete(m.nodeErrs, n)
+		n.SetState(n.State() &^ NodeError)
+	}
+}
+
+// unloaded reports whether the children of the node are yet to be loaded, see Loader.
+func unloaded(n Node) bool {
+	_, ok := n.(Loader)
+	return ok && isCollapsible(n) && !hasChildren(n) && !n.State().Is(NodeLoaded)
+}
+
+// loadChildren dispatches the LoadChildren command of the node the loading row belongs to,
+// unless it has been dispatched already.
+func loadChildren(row Node) tea.Cmd {
+	p, ok := row.(*placeholder)
+	if !ok || !unloaded(p.parent) || failed(p.parent) || p.parent.State().Is(NodeLoading) {
+		return nil
+	}
+	n := p.parent
+	n.SetState(n.State() | NodeLoading)
+	return n.(Loader).LoadChildren()
+}
+
+// applyLoadedChildren splices in the children loaded by a LoadChildren command.
+// The messages without a node are dropped.
+func (m *Model) applyLoadedChildren(msg ChildrenLoadedMsg) {
+	n := msg.Node
+	if isNil(n) {
+		return
+	}
+	n.SetState(n.State() &^ NodeLoading)
+	if msg.Err != nil {
+		m.SetNodeError(n, msg.Err)
+		return
+	}
+	delete(m.nodeErrs, n)
+	n.SetState(n.State() &^ NodeError)
+	n.SetState(n.State() | NodeLoaded)
+	if !m.ReplaceChildren(n, msg.Children) {
+		m.Refresh()
+	}
+}
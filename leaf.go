@@ -0,0 +1,76 @@
+package tree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// SetLeafOnly turns the leaf-only mode on or off.
+// In the leaf-only mode only the nodes which can't be expanded can hold the
+// cursor, the rest are there just for navigation and get expanded once the
+// cursor tries to land on them. Useful for pickers where choosing a directory
+// or a category isn't valid.
+func (m *Model) SetLeafOnly(on bool) tea.Cmd {
+	m.leafOnly = on
+	if !on || len(m.nodes) == 0 || isLeaf(m.currentNode()) {
+		return noop
+	}
+	return m.jumpToLeaf(m.cursor, 1)
+}
+
+// LeafOnly reports whether the leaf-only mode is on.
+func (m Model) LeafOnly() bool {
+	return m.leafOnly
+}
+
+// moveLeaves moves the cursor by n leaves, down for dir = 1 and up for dir = -1.
+func (m *Model) moveLeaves(n int, dir int) tea.Cmd {
+	row := m.cursor
+	for ; n > 0; n-- {
+		next := m.nextLeaf(row, dir)
+		if next == -1 {
+			break
+		}
+		row = next
+	}
+	return m.jumpTo(row)
+}
+
+// jumpToLeaf moves the cursor to the first leaf after the given row in the given direction.
+func (m *Model) jumpToLeaf(row int, dir int) tea.Cmd {
+	if next := m.nextLeaf(row, dir); next != -1 {
+		return m.jumpTo(next)
+	}
+	return noop
+}
+
+// jumpTo moves the cursor to the given row and scrolls it into view.
+func (m *Model) jumpTo(row int) tea.Cmd {
+	cmd := m.setCursor(row)
+	m.scrollToCursor()
+	return cmd
+}
+
+// nextLeaf returns the row of the first leaf after (dir = 1) or before (dir = -1)
+// the given row, expanding the collapsed nodes along the way, or -1 if there is none.
+// Expanding a node shifts the rows below it, the cursor is kept on the same node.
+func (m *Model) nextLeaf(row int, dir int) int {
+	for i := row + dir; 0 <= i && i < len(m.nodes); i += dir {
+		n := m.nodes[i]
+		if isPlaceholder(n) {
+			continue
+		}
+		if isLeaf(n) {
+			return i
+		}
+		if isExpanded(n) {
+			continue
+		}
+
+		before := len(m.nodes)
+		m.setCollapsed(n, false)
+		m.refreshAndSelect(m.currentNode())
+		if dir < 0 {
+			// the children showed up below the node, go through them first
+			i += len(m.nodes) - before + 1
+		}
+	}
+	return -1
+}
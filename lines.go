@@ -0,0 +1,34 @@
+package tree
+
+import "strings"
+
+// frame keeps the lines of the last view, so the changes since can be computed.
+type frame struct {
+	lines []string
+}
+
+// Lines returns the rows of the tree's view, the same ones View would join.
+// Just like View, it marks the lines as drawn for the purposes of ChangedLines.
+func (m Model) Lines() []string {
+	lines := strings.Split(m.compose(), "\n")
+	m.frame.lines = lines
+	return lines
+}
+
+// ChangedLines returns the indices of the rows of the view which changed
+// since the last call to View or Lines, so that the hosts drawing the lines
+// themselves can redraw only those.
+// If the number of rows has changed, the rows which appeared or disappeared
+// are reported as changed as well.
+func (m Model) ChangedLines() []int {
+	current := strings.Split(m.compose(), "\n")
+	previous := m.frame.lines
+
+	changed := []int{}
+	for i := 0; i < max(len(current), len(previous)); i++ {
+		if i >= len(current) || i >= len(previous) || current[i] != previous[i] {
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,64 @@
+package tree
+
+import (
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// WithSpinner sets the spinner shown while the tree is loading, see SetLoading.
+func WithSpinner(s spinner.Spinner) Option {
+	return func(m *Model) {
+		m.spinner.Spinner = s
+	}
+}
+
+// WithLoadingMessage sets the message shown next to the spinner while the tree is loading, see SetLoading.
+func WithLoadingMessage(msg string) Option {
+	return func(m *Model) {
+		m.loadingMessage = msg
+	}
+}
+
+// SetLoading shows a spinner, along with a message, in the middle of the tree, e.g. while
+// the host is fetching the nodes asynchronously. It's turned off by SetNodes.
+// The returned command starts the spinner.
+func (m *Model) SetLoading(on bool) tea.Cmd {
+	if m.loading == on {
+		return noop
+	}
+	m.loading = on
+	if !on {
+		return noop
+	}
+	// a new spinner drops the ticks of the previous one, so they don't stack up
+	m.spinner = spinner.New(spinner.WithSpinner(m.spinner.Spinner))
+	return m.spinner.Tick
+}
+
+// Loading reports whether the tree is loading, see SetLoading.
+func (m Model) Loading() bool {
+	return m.loading
+}
+
+// updateSpinner advances the spinner while the tree is loading, and lets it stop otherwise.
+func (m *Model) updateSpinner(msg spinner.TickMsg) tea.Cmd {
+	if !m.loading {
+		return noop
+	}
+	var cmd tea.Cmd
+	m.spinner, cmd = m.spinner.Update(msg)
+	return cmd
+}
+
+// loadingView renders the spinner and the message centered in place of the viewport.
+func (m Model) loadingView() string {
+	msg := m.loadingMessage
+	if msg == "" {
+		msg = m.tr(TextLoading)
+	}
+	s := m.spinner
+	s.Style = m.Styles.Loading
+	text := m.fitWidth(lipgloss.JoinHorizontal(lipgloss.Top, s.View(), " ", m.Styles.Loading.Render(msg)))
+	return lipgloss.Place(m.Width(), m.view.Height, lipgloss.Center, lipgloss.Center, text)
+}
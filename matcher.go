@@ -0,0 +1,141 @@
+package tree
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Match describes how a query matched a text.
+type Match struct {
+	// Score ranks the matches, the higher the better
+	Score int
+	// Positions are the byte offsets of the matched runes within the text
+	Positions []int
+}
+
+// Matcher is used for searching and filtering the nodes.
+type Matcher interface {
+	// Match reports whether the text matches the query, and how.
+	Match(query, text string) (Match, bool)
+}
+
+// SubstringMatcher matches the texts containing the query as is.
+// Earlier occurrences score higher.
+type SubstringMatcher struct{}
+
+func (SubstringMatcher) Match(query, text string) (Match, bool) {
+	spans := substringSpans(text, query)
+	if len(spans) == 0 {
+		return Match{}, false
+	}
+	positions := []int{}
+	for _, s := range spans {
+		for i := range text[s.start:s.end] {
+			positions = append(positions, s.start+i)
+		}
+	}
+	return Match{Score: -spans[0].start, Positions: positions}, true
+}
+
+// FuzzyMatcher matches the texts containing all of the runes of the query,
+// in the same order, but not necessarily next to each other, like fzf does.
+// Consecutive runes and runes at the start of words score higher.
+type FuzzyMatcher struct{}
+
+const (
+	fuzzyConsecutiveBonus = 5
+	fuzzyBoundaryBonus    = 3
+)
+
+func (FuzzyMatcher) Match(query, text string) (Match, bool) {
+	if query == "" {
+		return Match{}, false
+	}
+
+	m := Match{}
+	previous := -1 // byte offset of the previously matched rune
+	offset := 0
+	for _, q := range query {
+		i := strings.IndexRune(text[offset:], q)
+		if i == -1 {
+			return Match{}, false
+		}
+		pos := offset + i
+
+		m.Score++
+		if previous != -1 && pos == offset {
+			m.Score += fuzzyConsecutiveBonus
+		} else if isWordStart(text, pos) {
+			m.Score += fuzzyBoundaryBonus
+		}
+		if previous != -1 {
+			// penalizing the gaps between the matched runes
+			m.Score -= pos - offset
+		}
+
+		m.Positions = append(m.Positions, pos)
+		previous = pos
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		offset = pos + size
+	}
+	return m, true
+}
+
+// isWordStart reports whether the rune at the given offset starts a word,
+// e.g. it follows a separator or it's an upper case letter following a lower case one.
+func isWordStart(text string, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(text[:pos])
+	cur, _ := utf8.DecodeRuneInString(text[pos:])
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(cur)
+}
+
+// SetMatcher sets the matcher used for searching, filtering by a query and
+// highlighting the matches. Setting nil restores the SubstringMatcher.
+func (m *Model) SetMatcher(matcher Matcher) {
+	m.matcher = matcher
+}
+
+// match matches the query against the text using the model's matcher,
+// ignoring the case according to the case mode.
+func (m Model) match(query, text string) (Match, bool) {
+	if query == "" {
+		return Match{}, false
+	}
+	var matcher Matcher = SubstringMatcher{}
+	if m.matcher != nil {
+		matcher = m.matcher
+	}
+	if m.ignoreCase(query) {
+		if _, ok := matcher.(*RegexpMatcher); ok {
+			// lower casing the pattern would break escapes like \W
+			query = "(?i)" + query
+		} else {
+			query, text = foldCase(query), foldCase(text)
+		}
+	}
+	return matcher.Match(query, text)
+}
+
+// spans merges the positions of the matched runes into continuous spans.
+func (mt Match) spans(text string) []span {
+	spans := []span{}
+	for _, pos := range mt.Positions {
+		if pos < 0 || pos >= len(text) {
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[pos:])
+		if l := len(spans) - 1; l >= 0 && spans[l].end == pos {
+			spans[l].end += size
+			continue
+		}
+		spans = append(spans, span{start: pos, end: pos + size})
+	}
+	return spans
+}
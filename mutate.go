@@ -0,0 +1,198 @@
+package tree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// ChildSetter is an optional interface for nodes whose children can be changed
+// after the model has been created, see InsertNode, RemoveNode and ReplaceChildren.
+type ChildSetter interface {
+	// SetChildren should replace the children, so that the next call to Children() returns them.
+	SetChildren(Nodes)
+}
+
+// AddNodesMsg inserts the nodes among the children of the parent at the given index,
+// or after the last one if it's out of range, see InsertNode. Like the rest of the
+// messages changing the tree, it can be sent by the commands and the goroutines
+// producing the nodes, which mustn't touch the model themselves.
+type AddNodesMsg struct {
+	Parent Node
+	Index  int
+	Nodes  Nodes
+}
+
+// RemoveNodeMsg removes the node, see RemoveNode.
+type RemoveNodeMsg struct {
+	Node Node
+}
+
+// SetStateMsg sets the state of the node, e.g. collapses or marks it. The hints computed
+// by the model, i.e. NodeSelected, NodeLastChild and NodeHasPreviousSibling, are kept.
+type SetStateMsg struct {
+	Node  Node
+	State NodeState
+}
+
+// computedStates are the states of the nodes which are up to the model.
+const computedStates = NodeSelected | NodeLastChild | NodeHasPreviousSibling
+
+// applyMutation applies one of the messages changing the tree.
+func (m *Model) applyMutation(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case AddNodesMsg:
+		m.insertNodes(msg.Parent, msg.Index, msg.Nodes)
+	case RemoveNodeMsg:
+		m.RemoveNode(msg.Node)
+	case SetStateMsg:
+		if isNil(msg.Node) {
+			return
+		}
+		msg.Node.SetState(msg.State&^computedStates | msg.Node.State()&computedStates)
+		m.Refresh()
+	}
+}
+
+// InsertNode inserts the node among the children of the parent at the given index,
+// or after the last one if the index is out of range. A nil parent inserts a top level node.
+// The node's Parent() should already return the parent.
+//
+// The inserted nodes are expanded according to the expansion policy given to New, and
+// hidden or filtered just like the rest of them. The cursor stays on the same node.
+// It reports whether the tree has changed, i.e. false if the parent doesn't implement ChildSetter,
+// or the node is nil.
+func (m *Model) InsertNode(parent Node, i int, n Node) bool {
+	return m.insertNodes(parent, i, Nodes{n})
+}
+
+// insertNodes inserts the nodes among the children of the parent at the given index, see InsertNode.
+// The nil nodes are skipped.
+func (m *Model) insertNodes(parent Node, i int, ns Nodes) bool {
+	added := make(Nodes, 0, len(ns))
+	for _, n := range ns {
+		if !isNil(n) {
+			added = append(added, n)
+		}
+	}
+	if len(added) == 0 {
+		return false
+	}
+	ns = added
+	children, ok := m.childrenOf(parent)
+	if !ok {
+		return false
+	}
+	if i < 0 || i > len(children) {
+		i = len(children)
+	}
+	res := make(Nodes, 0, len(children)+len(ns))
+	res = append(res, children[:i]...)
+	res = append(res, ns...)
+	res = append(res, children[i:]...)
+	return m.setChildren(parent, res, ns)
+}
+
+// RemoveNode removes the node, along with its descendants, from the children of its parent.
+// If the cursor was on one of them it stays on the same row.
+// It reports whether the tree has changed, i.e. false if the node is nil or isn't in the tree,
+// or its parent doesn't implement ChildSetter.
+func (m *Model) RemoveNode(n Node) bool {
+	if isNil(n) {
+		return false
+	}
+	children, ok := m.childrenOf(n.Parent())
+	if !ok {
+		return false
+	}
+	i := children.index(n)
+	if i == -1 {
+		return false
+	}
+	res := make(Nodes, 0, len(children)-1)
+	res = append(res, children[:i]...)
+	res = append(res, children[i+1:]...)
+	return m.setChildren(n.Parent(), res, nil)
+}
+
+// ReplaceChildren replaces all of the children of the parent, e.g. when a watched directory changes.
+// A nil parent replaces the top level nodes, but unlike SetNodes nothing is carried over
+// from the replaced nodes. The children's Parent() should already return the parent.
+// It reports whether the tree has changed, i.e. false if the parent doesn't implement ChildSetter.
+func (m *Model) ReplaceChildren(parent Node, children Nodes) bool {
+	if _, ok := m.childrenOf(parent); !ok {
+		return false
+	}
+	return m.setChildren(parent, append(Nodes{}, children...), children)
+}
+
+// childrenOf returns the children of the parent, or the top level nodes for a nil parent,
+// and whether they can be changed.
+func (m Model) childrenOf(parent Node) (Nodes, bool) {
+	if parent == nil {
+		return m.tree, true
+	}
+	if isNil(parent) {
+		return nil, false
+	}
+	if _, ok := parent.(ChildSetter); !ok {
+		return nil, false
+	}
+	return parent.Children(), true
+}
+
+// setChildren replaces the children of the parent and prepares the added nodes like New does.
+func (m *Model) setChildren(parent Node, children, added Nodes) bool {
+	if parent == nil {
+		m.tree = children
+	} else {
+		parent.(ChildSetter).SetChildren(children)
+	}
+
+	for _, n := range added.all() {
+		n.SetState(n.State() &^ NodeSelected)
+	}
+	added.expand(m.expansion)
+	m.Refresh()
+	return true
+}
+
+// Refresh re-flattens and re-renders the whole tree. It's the way to show the changes made
+// to the nodes from the outside of the model, e.g. their names, states or children.
+// The hidden nodes, the filter and the search are applied again, and the nodes which
+// aren't in the tree anymore are dropped from the pins and the history.
+// The cursor stays on the same node, or on the same row if the node is gone.
+func (m *Model) Refresh() {
+	current := m.currentNode()
+	kept := map[Node]Node{}
+	for _, n := range m.tree.all() {
+		kept[n] = n
+	}
+	m.pinned = replace(m.pinned, kept)
+	m.zoom = replace(m.zoom, kept)
+	m.history = replace(m.history, kept)
+	m.hidden = replace(m.hidden, kept)
+	for n := range m.placeholders {
+		if _, ok := kept[n]; !ok {
+			delete(m.placeholders, n)
+		}
+	}
+	for n := range m.nodeErrs {
+		if _, ok := kept[n]; !ok {
+			delete(m.nodeErrs, n)
+		}
+	}
+	for n := range m.chunks {
+		if _, ok := kept[n]; !ok && n != nil {
+			delete(m.chunks, n)
+		}
+	}
+
+	m.unfilter()
+	m.hideNodes()
+	m.applyFilter()
+	if m.search.query != "" {
+		m.findMatches(m.search.query)
+		m.search.current = m.search.matches.index(current)
+	}
+	if _, ok := kept[current]; !ok && !isPlaceholder(current) {
+		current = nil
+	}
+	m.refreshAndSelect(current)
+}
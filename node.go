@@ -1,5 +1,13 @@
 package tree
 
+import (
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// NodeState is a set of flags describing how a node is displayed.
+// The bits covered by NodeUserStates are reserved for the application, see UserState.
 type NodeState uint16
 
 // Node represents the base model for the elements of the Treeish implementation
@@ -18,6 +26,24 @@ type Node interface {
 	SetState(NodeState)
 }
 
+// Alignment holds the horizontal alignment of each column of a row within the width given to it.
+// The zero value aligns everything to the left.
+type Alignment struct {
+	Name lipgloss.Position
+}
+
+// Aligner is an optional interface for nodes whose columns are aligned differently from the rest of the tree,
+// e.g. right-aligned numeric values.
+type Aligner interface {
+	Alignment() Alignment
+}
+
+// NodeStyler is an optional interface for nodes styled differently from the rest of the tree,
+// e.g. colored directories or executables. The Selected and Marked styles are applied on top of it.
+type NodeStyler interface {
+	Style() lipgloss.Style
+}
+
 // Nodes is a slice of Node elements, usually representing the children of a Node.
 type Nodes []Node
 
@@ -36,38 +62,45 @@ const (
 	NodeLastChild
 	// NodeHasPreviousSibling shows if the node has siblings
 	NodeHasPreviousSibling
+	// NodeMarked hints that the current node is a part of the user's selection, e.g. checked for a bulk action
+	NodeMarked
+	// NodeHydrated shows that the Hydrate command of the node has already been dispatched
+	NodeHydrated
+	// NodeLoading shows that the LoadChildren command of the node has been dispatched, but hasn't finished yet
+	NodeLoading
+	// NodeLoaded shows that the children of the node have been loaded by its LoadChildren command
+	NodeLoaded
+	// NodeError hints that something went wrong with the node, e.g. its children couldn't be loaded, see SetNodeError
+	NodeError
 )
 
-// at returns the i-th non hidden node
-// should be the same as ns.flatten()[i], but more performant (exits early)
-func (ns Nodes) at(i int) Node {
-	j := 0
-	for _, n := range ns {
-		if isHidden(n) {
-			continue
-		}
-		if j == i {
-			return n
-		}
+// NodeUserStates is the range of the high bits reserved for the application's own flags,
+// e.g. "modified" or "ignored". The model never sets nor clears them. The built-in
+// states use the bits below it, new ones are added from the bottom up.
+const NodeUserStates NodeState = 0xf000
 
-		if isExpanded(n) {
-			if nn := n.Children().at(i - j - 1); nn != nil {
-				return nn
-			}
-			j += countNodesBelow(n)
-		}
-		j++
+// userStatesShift is the position of the lowest bit reserved for the application
+const userStatesShift = 12
+
+// UserState returns the i-th state reserved for the application, or NodeNone if i isn't in [0, 4).
+//
+//	var Modified = tree.UserState(0)
+//	n.SetState(n.State().With(Modified))
+func UserState(i int) NodeState {
+	if i < 0 || i >= 16-userStatesShift {
+		return NodeNone
 	}
-	return nil
+	return 1 << (userStatesShift + i)
 }
 
-// countNodesBelow returns the number of all nodes below the given one
-func countNodesBelow(n Node) int {
-	count := 0
-	for _, child := range n.Children() {
-		count += countNodesBelow(child)
+// index returns the position of the given node in the slice, or -1 if not present
+func (ns Nodes) index(n Node) int {
+	for i, nn := range ns {
+		if nn == n {
+			return i
+		}
 	}
-	return count
+	return -1
 }
 
 // getDepth traverses through Parents (upwards) until it reaches nil
@@ -83,26 +116,159 @@ func getDepth(n Node) int {
 	return d
 }
 
-// flatten returns a flat slice of all non-hidden and expanded Nodes
-func (ns Nodes) flatten() Nodes {
+// Ancestors returns all of the ancestors of the node, from its parent up to the top level node.
+func Ancestors(n Node) Nodes {
 	res := Nodes{}
-	for _, n := range ns {
-		if isHidden(n) {
-			continue
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		res = append(res, p)
+	}
+	return res
+}
+
+// Descendants returns all of the nodes below the given one, depth first, in the order of Children().
+// Hidden and collapsed nodes are included.
+func Descendants(n Node) Nodes {
+	return n.Children().all()
+}
+
+// SiblingIndex returns the position of the node among the children of its parent,
+// or -1 if it has no parent.
+func SiblingIndex(n Node) int {
+	p := n.Parent()
+	if p == nil {
+		return -1
+	}
+	return p.Children().index(n)
+}
+
+// flatten returns a flat slice of all non-hidden and expanded Nodes.
+// Siblings keep the order returned by Children(), unless less is set, in which
+// case they are stably sorted by it.
+// Along the way the hints depending on the position of a node among its
+// (visible) siblings are recomputed.
+func (ns Nodes) flatten(less func(a, b Node) bool) Nodes {
+	res := Nodes{}
+	traverse(ns.visible().sorted(less), 0, func(n Node, i int, siblings Nodes, _ int) Nodes {
+		hints := n.State() &^ (NodeHasPreviousSibling | NodeLastChild)
+		if i > 0 {
+			hints |= NodeHasPreviousSibling
+		}
+		if i == len(siblings)-1 {
+			hints |= NodeLastChild
 		}
+		if hasChildren(n) {
+			hints |= NodeCollapsible
+		}
+		n.SetState(hints)
+
 		res = append(res, n)
 		if isCollapsible(n) && isExpanded(n) {
-			res = append(res, n.Children().flatten()...)
+			return n.Children().visible().sorted(less)
 		}
+		return nil
+	})
+	return res
+}
+
+// ordered returns all of the non-hidden nodes in the order they would be
+// rendered in, if every node was expanded
+func (ns Nodes) ordered(less func(a, b Node) bool) Nodes {
+	res := Nodes{}
+	traverse(ns.visible().sorted(less), 0, func(n Node, _ int, _ Nodes, _ int) Nodes {
+		res = append(res, n)
+		return n.Children().visible().sorted(less)
+	})
+	return res
+}
+
+// level holds the siblings being visited by traverse, and the position of the next one.
+type level struct {
+	siblings Nodes
+	next     int
+	depth    int
+}
+
+// traverse calls visit for the nodes, depth-first, descending into the children it returns
+// before moving on to the next sibling. Along with the node, visit gets its position among
+// the siblings and its depth, counted from the given one.
+// It keeps a stack of its own instead of recursing, so the depth of the tree is limited only
+// by the memory, and the flattened results don't get copied over at every level.
+func traverse(ns Nodes, depth int, visit func(n Node, i int, siblings Nodes, depth int) Nodes) {
+	stack := []level{{siblings: ns, depth: depth}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next == len(top.siblings) {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		i, siblings, d := top.next, top.siblings, top.depth
+		top.next++
+		if children := visit(siblings[i], i, siblings, d); len(children) > 0 {
+			stack = append(stack, level{siblings: children, depth: d + 1})
+		}
+	}
+}
+
+// visible returns the nodes which are not hidden, skipping the nil ones
+func (ns Nodes) visible() Nodes {
+	res := make(Nodes, 0, len(ns))
+	for _, n := range ns {
+		if !isNil(n) && !isHidden(n) {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// sorted returns a stably sorted copy of the nodes, or the nodes themselves if less is nil
+func (ns Nodes) sorted(less func(a, b Node) bool) Nodes {
+	if less == nil {
+		return ns
 	}
+	res := make(Nodes, len(ns))
+	copy(res, ns)
+	sort.SliceStable(res, func(i, j int) bool {
+		return less(res[i], res[j])
+	})
 	return res
 }
 
+// all returns a flat slice of all Nodes, regardless of their state, skipping the nil ones
+func (ns Nodes) all() Nodes {
+	res := Nodes{}
+	ns.walk(0, func(n Node, _ int) {
+		res = append(res, n)
+	})
+	return res
+}
+
+// walk calls fn for every node and its descendants, depth-first, regardless of their state,
+// skipping the nil ones
+func (ns Nodes) walk(depth int, fn func(n Node, depth int)) {
+	traverse(ns, depth, func(n Node, _ int, _ Nodes, depth int) Nodes {
+		if isNil(n) {
+			return nil
+		}
+		fn(n, depth)
+		return n.Children()
+	})
+}
+
 // Is checks if the given state is set
 func (s NodeState) Is(st NodeState) bool {
 	return s&st == st
 }
 
+// With returns the states with the given one set
+func (s NodeState) With(st NodeState) NodeState {
+	return s | st
+}
+
+// Without returns the states with the given one cleared
+func (s NodeState) Without(st NodeState) NodeState {
+	return s &^ st
+}
+
 func isHidden(n Node) bool {
 	return n.State().Is(NodeHidden)
 }
@@ -115,6 +281,10 @@ func isCollapsible(n Node) bool {
 	return n.State().Is(NodeCollapsible)
 }
 
+func isLeaf(n Node) bool {
+	return !isCollapsible(n)
+}
+
 func isLastNode(n Node) bool {
 	return n.State().Is(NodeLastChild)
 }
@@ -123,6 +293,10 @@ func isSelected(n Node) bool {
 	return n.State().Is(NodeSelected)
 }
 
+func isMarked(n Node) bool {
+	return n.State().Is(NodeMarked)
+}
+
 func hasPreviousSibling(n Node) bool {
 	return n.State().Is(NodeHasPreviousSibling)
 }
@@ -0,0 +1,33 @@
+package tree
+
+// SetNodeError marks the node with NodeError, e.g. when its children couldn't be loaded,
+// so it's rendered with the Error style instead of looking empty. The message of the
+// error is shown in place of the children of the node while it's expanded.
+// A nil err clears the error.
+func (m *Model) SetNodeError(n Node, err error) {
+	if err == nil {
+		n.SetState(n.State() &^ NodeError)
+		delete(m.nodeErrs, n)
+	} else {
+		n.SetState(n.State() | NodeError)
+		m.nodeErrs[n] = err
+	}
+	m.refreshAndSelect(m.currentNode())
+}
+
+// NodeErr returns the error the node was marked with by SetNodeError, or nil if it has none.
+func (m Model) NodeErr(n Node) error {
+	return m.nodeErrs[n]
+}
+
+// failed reports whether the node is marked with NodeError.
+func failed(n Node) bool {
+	return n.State().Is(NodeError)
+}
+
+// placeholderFailed reports whether the placeholder belongs to a failed node, and
+// should be rendered with the Error style.
+func placeholderFailed(n Node) bool {
+	p, ok := n.(*placeholder)
+	return ok && failed(p.parent)
+}
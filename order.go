@@ -0,0 +1,166 @@
+package tree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// OrderChangedMsg is emitted when installing a sort function or moving a
+// node changes the order of the visible nodes.
+type OrderChangedMsg struct {
+	// Nodes are the visible nodes in their new order
+	Nodes Nodes
+}
+
+// SetSortFunc installs the function used for ordering the siblings at every
+// level of the tree. The sort is stable, so siblings that are equal keep the
+// order returned by Children().
+//
+// Without a sort function, which is the default, the siblings are rendered
+// exactly in the order returned by Children(). Passing nil restores that.
+//
+// The cursor stays on the same node. If the order of the visible nodes has
+// changed, the returned command emits an OrderChangedMsg.
+func (m *Model) SetSortFunc(less func(a, b Node) bool) tea.Cmd {
+	previous := m.nodes
+	current := m.currentNode()
+
+	m.less = less
+	m.refreshAndSelect(current)
+
+	if sameOrder(previous, m.nodes) {
+		return noop
+	}
+	nodes := m.nodes
+	return func() tea.Msg {
+		return OrderChangedMsg{Nodes: nodes}
+	}
+}
+
+// Sort orders the siblings at every level of the tree with the given function, keeping the
+// cursor on the same node, e.g. for the "sort by name/size/modified" toggles, see SetSortFunc.
+// The order is kept as the tree changes, until Sort or SetSortFunc is called again.
+func (m *Model) Sort(less func(a, b Node) bool) tea.Cmd {
+	return m.SetSortFunc(less)
+}
+
+// ByName orders the nodes by their names, ignoring the case and the escape sequences, see SetSortFunc.
+func ByName(a, b Node) bool {
+	an, bn := stripANSI(a.Name()), stripANSI(b.Name())
+	if af, bf := foldCase(an), foldCase(bn); af != bf {
+		return af < bf
+	}
+	return an < bn
+}
+
+// Reverse returns the sort function ordering the nodes the other way around, e.g. for toggling
+// between the ascending and the descending order of the same key, see SetSortFunc.
+func Reverse(less func(a, b Node) bool) func(a, b Node) bool {
+	return func(a, b Node) bool {
+		return less(b, a)
+	}
+}
+
+// ParentsFirst returns the sort function ordering the nodes with children before the leaves,
+// like the directories before the files, and by the given sort function otherwise.
+func ParentsFirst(less func(a, b Node) bool) func(a, b Node) bool {
+	return func(a, b Node) bool {
+		if ap, bp := hasChildren(a) || isCollapsible(a), hasChildren(b) || isCollapsible(b); ap != bp {
+			return ap
+		}
+		return less(a, b)
+	}
+}
+
+// sameOrder reports whether both slices contain the same nodes in the same order.
+func sameOrder(a, b Nodes) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ChildSwapper is an optional interface for nodes whose children can be
+// reordered with MoveNodeUp and MoveNodeDown.
+type ChildSwapper interface {
+	// SwapChildren should swap the children at the given indices, so that
+	// the next call to Children() reflects the new order. The indices are
+	// always adjacent.
+	SwapChildren(i, j int)
+}
+
+// NodeMovedMsg is emitted when a node is moved past one of its siblings,
+// so the host can persist the new order. The hidden siblings in between
+// shift by one, keeping their places relative to the visible ones.
+type NodeMovedMsg struct {
+	Node Node
+	// From and To are the indices of the node among the children of its parent
+	From, To int
+}
+
+// MoveNodeUp swaps the node pointed at by m.cursor with its previous visible sibling.
+// Nodes which aren't top level can be moved only if their parent implements ChildSwapper.
+// The nodes aren't moved while a sort function is installed, it dictates their order.
+func (m *Model) MoveNodeUp() tea.Cmd {
+	return m.moveNode(-1)
+}
+
+// MoveNodeDown swaps the node pointed at by m.cursor with its next visible sibling.
+// Nodes which aren't top level can be moved only if their parent implements ChildSwapper.
+// The nodes aren't moved while a sort function is installed, it dictates their order.
+func (m *Model) MoveNodeDown() tea.Cmd {
+	return m.moveNode(1)
+}
+
+// moveNode moves the current node past the first visible sibling in the given direction,
+// swapping it with one adjacent sibling at a time, so the hidden ones in between stay in their order.
+func (m *Model) moveNode(dir int) tea.Cmd {
+	if len(m.nodes) == 0 || m.less != nil {
+		return noop
+	}
+	n := m.currentNode()
+	if isPlaceholder(n) {
+		return noop
+	}
+
+	siblings := m.tree
+	swap := func(i, j int) {
+		m.tree[i], m.tree[j] = m.tree[j], m.tree[i]
+	}
+	if parent := n.Parent(); parent != nil {
+		swapper, ok := parent.(ChildSwapper)
+		if !ok {
+			return noop
+		}
+		siblings = parent.Children()
+		swap = swapper.SwapChildren
+	}
+
+	from := siblings.index(n)
+	if from == -1 {
+		return noop
+	}
+	to := from + dir
+	for 0 <= to && to < len(siblings) && isHidden(siblings[to]) {
+		to += dir
+	}
+	if to < 0 || to >= len(siblings) {
+		return noop
+	}
+
+	previous := m.nodes
+	for i := from; i != to; i += dir {
+		swap(i, i+dir)
+	}
+	m.refreshAndSelect(n)
+
+	moved := NodeMovedMsg{Node: n, From: from, To: to}
+	cmds := []tea.Cmd{func() tea.Msg { return moved }}
+	if !sameOrder(previous, m.nodes) {
+		nodes := m.nodes
+		cmds = append(cmds, func() tea.Msg { return OrderChangedMsg{Nodes: nodes} })
+	}
+	return tea.Batch(cmds...)
+}
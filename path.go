@@ -0,0 +1,87 @@
+package tree
+
+import "strings"
+
+// FindPath returns the node at the given path of names, joined with PathSeparator,
+// e.g. "cmd/server/main.go", starting with the name of the top level node.
+// Every part of the path can also be the ID of the node, see Identifiable.
+// Hidden and collapsed nodes are found too, nil is returned if there's no such node.
+func (m Model) FindPath(path string) Node {
+	var found Node
+	candidates := m.tree
+	for _, part := range strings.Split(strings.Trim(path, PathSeparator), PathSeparator) {
+		found = nil
+		for _, n := range candidates {
+			if isNil(n) {
+				continue
+			}
+			if stripANSI(n.Name()) == part {
+				found = n
+				break
+			}
+			if id, ok := n.(Identifiable); ok && id.ID() == part {
+				found = n
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		candidates = found.Children()
+	}
+	return found
+}
+
+// WithInitialPath expands the ancestors of the node at the given path and selects it,
+// e.g. when the tree is opened to reveal a file, see FindPath.
+func WithInitialPath(path string) Option {
+	return func(m *Model) {
+		m.initialPath = path
+	}
+}
+
+// ExpandPath expands the node at the given path, along with all of its ancestors, see FindPath.
+// It reports whether the node was found.
+func (m *Model) ExpandPath(path string) bool {
+	n := m.FindPath(path)
+	if n == nil {
+		return false
+	}
+	m.saveUndo()
+	m.saveExpansion()
+	current := m.currentNode()
+	for p := n; p != nil; p = p.Parent() {
+		m.setCollapsed(p, false)
+	}
+	m.refreshAndSelect(current)
+	return true
+}
+
+// CollapsePath collapses the node at the given path, see FindPath. If the cursor was
+// on one of its descendants, it moves onto the node. It reports whether the node was found.
+func (m *Model) CollapsePath(path string) bool {
+	n := m.FindPath(path)
+	if n == nil {
+		return false
+	}
+	m.saveUndo()
+	m.saveExpansion()
+	current := m.currentNode()
+	if current != nil && Ancestors(current).index(n) != -1 {
+		current = n
+	}
+	m.setCollapsed(n, true)
+	m.refreshAndSelect(current)
+	return true
+}
+
+// SelectPath moves the cursor onto the node at the given path, expanding its ancestors
+// and scrolling it into view, e.g. for revealing the opened file in the tree, see FindPath.
+// It reports whether the node was found and could be selected, see RevealNode.
+func (m *Model) SelectPath(path string) bool {
+	n := m.FindPath(path)
+	if n == nil {
+		return false
+	}
+	return m.RevealNode(n)
+}
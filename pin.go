@@ -0,0 +1,128 @@
+package tree
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// PinIndicator is rendered in front of every row of the pinned section.
+const PinIndicator = "» "
+
+// PinNode pins the node to the separate section at the top of the tree.
+// Pinned nodes are shown regardless of them being collapsed or hidden.
+//
+// Moving the cursor up from the first row of the tree moves it into the section,
+// and moving it down from the last pinned row moves it back. Expanding the pinned
+// row jumps to its node in the tree, see RevealNode. The rows which don't
+// belong to an actual node, e.g. the loading rows, can't be pinned.
+func (m *Model) PinNode(n Node) {
+	if n == nil || isPlaceholder(n) || m.pinned.index(n) != -1 {
+		return
+	}
+	m.pinned = append(m.pinned, n)
+	m.layout()
+}
+
+// UnpinNode removes the node from the pinned section.
+func (m *Model) UnpinNode(n Node) {
+	i := m.pinned.index(n)
+	if i == -1 {
+		return
+	}
+	m.pinned = append(m.pinned[:i:i], m.pinned[i+1:]...)
+	if m.pinFocused && m.pinnedRows() == 0 {
+		m.pinFocused = false
+	}
+	m.pinCursor = clamp(m.pinCursor, 0, max(m.pinnedRows()-1, 0))
+	m.layout()
+}
+
+// TogglePin pins or unpins the node pointed at by m.cursor.
+func (m *Model) TogglePin() {
+	n := m.currentNode()
+	if m.pinned.index(n) != -1 {
+		m.UnpinNode(n)
+		return
+	}
+	m.PinNode(n)
+}
+
+// PinnedFocused reports whether the cursor is in the pinned section, and returns the node it's on.
+func (m Model) PinnedFocused() (Node, bool) {
+	if !m.pinFocused || m.pinCursor >= m.pinnedRows() {
+		return nil, false
+	}
+	return m.pinned[m.pinCursor], true
+}
+
+// focusPinned moves the cursor into the last row of the pinned section, if there's any.
+func (m *Model) focusPinned() {
+	if rows := m.pinnedRows(); rows > 0 {
+		m.pinFocused = true
+		m.pinCursor = rows - 1
+	}
+}
+
+// updatePinned handles the keys while the cursor is in the pinned section, the rest
+// of them move it back to the tree and are handled by it.
+func (m *Model) updatePinned(msg tea.KeyMsg) (tea.Cmd, bool) {
+	n, ok := m.PinnedFocused()
+	if !ok {
+		m.pinFocused = false
+		return noop, false
+	}
+	switch {
+	case key.Matches(msg, m.KeyMap.LineUp):
+		m.pinCursor = max(m.pinCursor-1, 0)
+	case key.Matches(msg, m.KeyMap.LineDown):
+		m.pinCursor++
+		m.pinFocused = m.pinCursor < m.pinnedRows()
+		m.pinCursor = min(m.pinCursor, m.pinnedRows()-1)
+	case key.Matches(msg, m.KeyMap.Expand):
+		m.pinFocused = false
+		m.RevealNode(n)
+		return m.hydrateVisible(), true
+	case key.Matches(msg, m.KeyMap.TogglePin):
+		m.UnpinNode(n)
+	case key.Matches(msg, m.KeyMap.Cancel):
+		m.pinFocused = false
+	default:
+		m.pinFocused = false
+		return noop, false
+	}
+	return noop, true
+}
+
+// Pinned returns the pinned nodes, in the order they were pinned.
+func (m Model) Pinned() Nodes {
+	return m.pinned
+}
+
+// pinnedRows returns the number of rows the pinned section takes up.
+// It never takes more than half of the tree height, so the tree itself stays usable.
+func (m Model) pinnedRows() int {
+	if m.height == 0 {
+		return len(m.pinned)
+	}
+	return min(len(m.pinned), m.height/2)
+}
+
+// pinnedView renders the first rows of the pinned nodes, with their full path
+// since they are shown out of the tree context.
+func (m Model) pinnedView(rows int) string {
+	lines := make([]string, 0, rows)
+	for i, n := range m.pinned[:rows] {
+		line := PinIndicator + nodePath(n)
+		if w := m.Width(); w > 0 && textWidth(line) > w {
+			line = m.Truncation.shorten(line, w)
+		}
+		style := m.Styles.Pinned
+		if m.pinFocused && i == m.pinCursor {
+			style = style.Copy().Inherit(m.Styles.Selected)
+		}
+		lines = append(lines, style.Render(line))
+	}
+	return strings.Join(lines, "\n")
+}
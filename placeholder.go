@@ -0,0 +1,65 @@
+package tree
+
+import "strings"
+
+// placeholder is the row rendered in place of the children of an expanded
+// node which has none, so it doesn't look like it hasn't been loaded yet.
+type placeholder struct {
+	parent Node
+	name   string
+	state  NodeState
+}
+
+func (p *placeholder) Name() string { return p.name }
+
+// Prefix keeps the names aligned with the ones of the real nodes.
+func (p *placeholder) Prefix() string {
+	return strings.Repeat(" ", textWidth(p.parent.Prefix()))
+}
+
+func (p *placeholder) Parent() Node          { return p.parent }
+func (p *placeholder) Children() Nodes       { return nil }
+func (p *placeholder) State() NodeState      { return p.state }
+func (p *placeholder) SetState(st NodeState) { p.state = st }
+
+// isPlaceholder reports whether the row doesn't belong to an actual node,
+// i.e. it's a placeholder, a row showing more children, or a removed node, see UpdateNodes.
+func isPlaceholder(n Node) bool {
+	switch n.(type) {
+	case *placeholder, *more, *ghost:
+		return true
+	}
+	return false
+}
+
+// withPlaceholders inserts a placeholder after every expanded node without any children,
+// a loading row if they are yet to be loaded, see Loader, or the error of the node, see SetNodeError.
+// The placeholders are reused between refreshes, so the cursor can stay on them.
+func (m Model) withPlaceholders(ns Nodes) Nodes {
+	empty, loading := m.tr(TextEmpty), m.tr(TextLoadingChildren)
+	res := make(Nodes, 0, len(ns))
+	for _, n := range ns {
+		res = append(res, n)
+		if !isCollapsible(n) || !isExpanded(n) || len(n.Children()) > 0 {
+			continue
+		}
+		text := empty
+		if err := m.nodeErrs[n]; err != nil {
+			text = err.Error()
+		} else if unloaded(n) && !failed(n) {
+			text = loading
+		}
+		if text == "" {
+			continue
+		}
+		p, ok := m.placeholders[n]
+		if !ok {
+			p = &placeholder{parent: n}
+			m.placeholders[n] = p
+		}
+		p.name = text
+		p.state = p.state&NodeSelected | NodeLastChild
+		res = append(res, p)
+	}
+	return res
+}
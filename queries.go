@@ -0,0 +1,57 @@
+package tree
+
+// queriesLimit is the maximum number of remembered queries.
+const queriesLimit = 100
+
+// queries remembers the search and filter queries, so they can be recalled
+// in the prompt like in a shell.
+type queries struct {
+	entries []string // the oldest first
+	pos     int      // the recalled entry, len(entries) if none
+	draft   string   // what was typed before the recalling started
+}
+
+// add remembers the query, unless it's empty or the same as the last one.
+func (q *queries) add(query string) {
+	if query == "" || (len(q.entries) > 0 && q.entries[len(q.entries)-1] == query) {
+		q.rewind()
+		return
+	}
+	q.entries = append(q.entries, query)
+	if len(q.entries) > queriesLimit {
+		q.entries = q.entries[len(q.entries)-queriesLimit:]
+	}
+	q.rewind()
+}
+
+// rewind starts the recalling from the newest query.
+func (q *queries) rewind() {
+	q.pos = len(q.entries)
+	q.draft = ""
+}
+
+// recall returns the older, or the newer if forward is set, query than the one being shown.
+// Going past the newest one returns the draft which was being typed.
+// It reports false if there's nowhere to go.
+func (q *queries) recall(forward bool, current string) (string, bool) {
+	pos := q.pos - 1
+	if forward {
+		pos = q.pos + 1
+	}
+	if pos < 0 || pos > len(q.entries) {
+		return "", false
+	}
+	if q.pos == len(q.entries) {
+		q.draft = current
+	}
+	q.pos = pos
+	if pos == len(q.entries) {
+		return q.draft, true
+	}
+	return q.entries[pos], true
+}
+
+// QueryHistory returns the entered search and filter queries, the oldest first.
+func (m Model) QueryHistory() []string {
+	return append([]string{}, m.queries.entries...)
+}
@@ -0,0 +1,95 @@
+package tree
+
+import "slices"
+
+// Identifiable is an optional interface for nodes with a stable identity, e.g.
+// the UID of a pod, used for matching the nodes when the tree is replaced
+// with SetNodes, which carries over their expanded and marked states, the pins
+// and the cursor, and for saving them with ViewState.
+//
+// The rest of the nodes are identified by the path of names from the top level
+// node, see PathSeparator, so renaming a node, or one of its ancestors, or having
+// several siblings of the same name, loses their states.
+type Identifiable interface {
+	ID() string
+}
+
+// reconciledStates are the states carried over to the matching nodes by SetNodes.
+const reconciledStates = NodeCollapsed | NodeMarked
+
+// nodeID returns the identity of the node, see Identifiable.
+func nodeID(n Node) string {
+	if id, ok := n.(Identifiable); ok {
+		return id.ID()
+	}
+	return nodePath(n)
+}
+
+// SetNodes replaces the nodes of the tree, e.g. with freshly loaded ones.
+// The nodes which were there before keep their expanded and marked states,
+// as well as the cursor, the pins and the place in the history, see Identifiable.
+// If the node under the cursor is gone, the cursor moves to its closest remaining ancestor.
+// The new ones are expanded according to the expansion policy given to New.
+// The undo history is dropped, since it refers to the replaced nodes.
+// It also ends the loading state, see SetLoading.
+func (m *Model) SetNodes(ns Nodes) {
+	m.loading = false
+	old := map[string]Node{}
+	for _, n := range m.tree.all() {
+		old[nodeID(n)] = n
+	}
+	selected := m.selectedNode()
+
+	ns.expand(m.expansion)
+	replaced := map[Node]Node{}
+	var target Node
+	for _, n := range ns.all() {
+		n.SetState(n.State() &^ NodeSelected)
+		prev, ok := old[nodeID(n)]
+		if !ok {
+			continue
+		}
+		n.SetState(n.State()&^reconciledStates | prev.State()&reconciledStates)
+		replaced[prev] = n
+		if prev == selected {
+			target = n
+		}
+	}
+	if target == nil && selected != nil {
+		for _, a := range Ancestors(selected) {
+			if r, ok := replaced[a]; ok {
+				target = r
+				break
+			}
+		}
+	}
+
+	m.tree = slices.Clone(ns)
+	m.pinned = replace(m.pinned, replaced)
+	m.zoom = replace(m.zoom, replaced)
+	m.history = replace(m.history, replaced)
+	m.undo, m.redo, m.expansionUndo = nil, nil, nil
+	m.placeholders = map[Node]*placeholder{}
+	m.chunks = map[Node]*more{}
+	m.nodeErrs = map[Node]error{}
+
+	m.filtered, m.hidden = nil, nil
+	m.hideNodes()
+	m.applyFilter()
+	if m.search.query != "" {
+		m.findMatches(m.search.query)
+		m.search.current = m.search.matches.index(target)
+	}
+	m.refreshAndSelect(target)
+}
+
+// replace swaps the nodes with their replacements, dropping the ones without any.
+func replace(ns Nodes, replaced map[Node]Node) Nodes {
+	res := Nodes{}
+	for _, n := range ns {
+		if r, ok := replaced[n]; ok {
+			res = append(res, r)
+		}
+	}
+	return res
+}
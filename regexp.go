@@ -0,0 +1,82 @@
+package tree
+
+import (
+	"errors"
+	"regexp"
+)
+
+// Validator is an optional interface for matchers whose queries can be malformed.
+// The error is shown in the prompt while the query is being typed, and the
+// matches of the last valid query are kept until it's fixed.
+type Validator interface {
+	Validate(query string) error
+}
+
+// RegexpMatcher matches the texts against the query as a regular expression,
+// e.g. `_test\.go$`. Earlier matches score higher.
+// The last compiled query is cached, so it's not safe for concurrent use.
+type RegexpMatcher struct {
+	query string
+	re    *regexp.Regexp
+	err   error
+}
+
+// compile compiles the query, unless it's the same as the last one.
+func (rm *RegexpMatcher) compile(query string) (*regexp.Regexp, error) {
+	if rm.re == nil && rm.err == nil || query != rm.query {
+		rm.query = query
+		rm.re, rm.err = regexp.Compile(query)
+	}
+	return rm.re, rm.err
+}
+
+func (rm *RegexpMatcher) Validate(query string) error {
+	_, err := rm.compile(query)
+	return err
+}
+
+func (rm *RegexpMatcher) Match(query, text string) (Match, bool) {
+	re, err := rm.compile(query)
+	if err != nil {
+		return Match{}, false
+	}
+	found := re.FindAllStringIndex(text, -1)
+	if len(found) == 0 {
+		return Match{}, false
+	}
+	positions := []int{}
+	for _, f := range found {
+		for i := range text[f[0]:f[1]] {
+			positions = append(positions, f[0]+i)
+		}
+	}
+	return Match{Score: -found[0][0], Positions: positions}, true
+}
+
+// ToggleRegexp switches the matcher between a RegexpMatcher and the one set before, see SetMatcher.
+func (m *Model) ToggleRegexp() {
+	if m.Regexp() {
+		m.matcher = m.plainMatcher
+		return
+	}
+	m.plainMatcher = m.matcher
+	m.matcher = &RegexpMatcher{}
+}
+
+// Regexp reports whether the queries are matched as regular expressions.
+func (m Model) Regexp() bool {
+	_, ok := m.matcher.(*RegexpMatcher)
+	return ok
+}
+
+// validateQuery returns the error shown in the prompt if the matcher can't use the query.
+func (m Model) validateQuery(query string) error {
+	v, ok := m.matcher.(Validator)
+	if !ok || query == "" {
+		return nil
+	}
+	if err := v.Validate(query); err != nil {
+		return errors.New(m.tr(TextInvalidPattern, query))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package tree
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// NodeRenamedMsg is emitted once the user confirms the new name of a node.
+// The tree doesn't change the node in any way, it's up to the host to persist
+// the new name and refresh the tree. Confirming an empty name cancels the renaming.
+type NodeRenamedMsg struct {
+	Node    Node
+	NewName string
+}
+
+// StartRename replaces the row of the node pointed at by m.cursor with an
+// editor containing the node's name. While editing, all key events are routed to the editor.
+// The rows which don't belong to an actual node, e.g. the loading rows, can't be renamed.
+func (m *Model) StartRename() tea.Cmd {
+	if len(m.nodes) == 0 || isPlaceholder(m.currentNode()) {
+		return noop
+	}
+
+	n := m.currentNode()
+	m.mode = modeRename
+	m.editor.Prompt = ""
+	m.editor.Width = m.editorWidth(n)
+	m.editor.SetValue(n.Name())
+	m.editor.CursorEnd()
+	cmd := m.editor.Focus()
+	m.rerenderNode(m.cursor)
+	return cmd
+}
+
+// editorWidth returns the width left for the editor next to the prefix, the indicator and
+// the icon of the node, without the gutter, the scrollbar, the columns and the actions.
+// Zero, i.e. unlimited, if the size is not known yet.
+func (m Model) editorWidth(n Node) int {
+	if m.Width() <= 0 {
+		return 0
+	}
+	decorations := m.renderPrefix(n) + m.renderIndicator(n) + m.renderIcon(n)
+	// leaving the last column empty, like the names do
+	return max(m.rowWidth()-textWidth(decorations)-1, 1)
+}
+
+// CancelRename stops editing the name of the node, discarding the changes.
+func (m *Model) CancelRename() {
+	m.mode = modeNormal
+	m.editor.Blur()
+	m.editor.Reset()
+	m.rerenderNode(m.cursor)
+}
+
+func (m Model) updateRename(msg tea.Msg) (Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.KeyMap.Cancel):
+			m.CancelRename()
+			return m, noop
+		case key.Matches(msg, m.KeyMap.Confirm):
+			renamed := NodeRenamedMsg{Node: m.currentNode(), NewName: m.editor.Value()}
+			m.CancelRename()
+			if strings.TrimSpace(renamed.NewName) == "" {
+				return m, noop
+			}
+			return m, func() tea.Msg { return renamed }
+		}
+	}
+
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	m.rerenderNode(m.cursor)
+	return m, cmd
+}
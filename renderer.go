@@ -0,0 +1,47 @@
+package tree
+
+import (
+	"reflect"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// WithRenderer renders all of the styles with the given renderer, see SetRenderer.
+func WithRenderer(r *lipgloss.Renderer) Option {
+	return func(m *Model) {
+		m.renderer = r
+	}
+}
+
+// SetRenderer renders all of the styles against the color profile and the background
+// of the given renderer instead of the global default one, e.g. for the SSH sessions
+// served with Wish, or a forced color profile in tests. The styles set with SetStyles
+// later on are bound to it too.
+func (m *Model) SetRenderer(r *lipgloss.Renderer) {
+	m.renderer = r
+	m.Styles = m.Styles.WithRenderer(r)
+	m.rerender()
+}
+
+// WithRenderer returns a copy of the styles bound to the given renderer.
+func (s Styles) WithRenderer(r *lipgloss.Renderer) Styles {
+	if r == nil {
+		return s
+	}
+	v := reflect.ValueOf(&s).Elem()
+	styleType := reflect.TypeOf(lipgloss.Style{})
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Type() == styleType {
+			f.Set(reflect.ValueOf(f.Interface().(lipgloss.Style).Copy().Renderer(r)))
+		}
+	}
+	if symbol, ok := s.Symbol.(Style); ok {
+		s.Symbol = Style(lipgloss.Style(symbol).Copy().Renderer(r))
+	}
+	if byDepth := s.SymbolByDepth; byDepth != nil {
+		s.SymbolByDepth = func(depth int) lipgloss.Style {
+			return byDepth(depth).Copy().Renderer(r)
+		}
+	}
+	return s
+}
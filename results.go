@@ -0,0 +1,89 @@
+package tree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// WithFlatResults lists the search results instead of the tree, see SetFlatResults.
+func WithFlatResults() Option {
+	return func(m *Model) {
+		m.flatResults = true
+	}
+}
+
+// SetFlatResults toggles listing the search results instead of the tree.
+// While a search query is active, only the matching nodes are shown, along
+// with the full path of their ancestors, e.g. `src/tree/node.go`.
+// Expand on a result clears the search and reveals the node in the tree.
+func (m *Model) SetFlatResults(on bool) {
+	if on == m.flatResults {
+		return
+	}
+	current := m.selectedNode()
+	m.flatResults = on
+	m.refreshAndSelect(current)
+}
+
+// FlatResults reports whether the search results are listed instead of the tree.
+func (m Model) FlatResults() bool {
+	return m.flatResults
+}
+
+// showingResults reports whether the search results are currently listed instead of the tree.
+func (m Model) showingResults() bool {
+	return m.flatResults && m.search.query != ""
+}
+
+// flattenNodes returns the rows to render, either the flattened tree, or the search results.
+func (m Model) flattenNodes() Nodes {
+	if m.showingResults() {
+		return append(Nodes{}, m.search.matches...)
+	}
+	return m.withGhosts(m.withPlaceholders(m.withChunks(m.roots().flatten(m.less))))
+}
+
+// selectResult clears the search and reveals the selected result in the tree.
+func (m *Model) selectResult() tea.Cmd {
+	n := m.currentNode()
+	m.ClearSearch()
+	if n != nil {
+		m.reveal(n)
+	}
+	return m.hydrateVisible()
+}
+
+// selectedNode returns the node holding the selection, even if it's not
+// among the rendered rows, e.g. when there are no search results.
+func (m Model) selectedNode() Node {
+	if n := m.currentNode(); n != nil && isSelected(n) {
+		return n
+	}
+	for _, n := range m.tree.all() {
+		if isSelected(n) {
+			return n
+		}
+	}
+	return nil
+}
+
+// renderResult renders the node as a search result, its name prefixed with the path of its ancestors.
+func (m Model) renderResult(n Node) string {
+	dir := ""
+	if p := n.Parent(); p != nil {
+		dir = nodePath(p) + PathSeparator
+	}
+	name := n.Name()
+	if m.Width() <= 0 {
+		return m.renderName(n, dir+name, textWidth(dir+name))
+	}
+
+	// leaving the last column empty, the name is shortened only once there's no room for the path
+	width := m.Width() - 1
+	if textWidth(name) >= width {
+		dir = ""
+		if textWidth(name) > width {
+			name = m.Truncation.shorten(name, width)
+		}
+	} else if dirWidth := width - textWidth(name); textWidth(dir) > dirWidth {
+		dir = m.Truncation.shorten(dir, dirWidth)
+	}
+	return m.renderName(n, dir+name, max(width, 0))
+}
@@ -0,0 +1,296 @@
+package tree
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The nodes may take up more than one row of the viewport, e.g. with a Description,
+// so the cursor (indexing the nodes) and the rows of the viewport are mapped onto
+// each other through the nodes taller than a single row, all of the rest take up one.
+//
+// Only the nodes in and near the viewport are rendered, see materialize, and only the
+// rows in the viewport are handed over to it, see viewportView. The heights of the nodes
+// are measured without rendering them, once per flattening of the tree, so a change of
+// the styles or the width costs as much as the viewport is tall.
+
+// tall is a node taking up more than one row.
+type tall struct {
+	index int // of the node
+	extra int // the rows of the node below its first one
+	above int // the extra rows of the tall nodes before it
+}
+
+// measureRows measures the heights of all of the nodes, dropping the rendered ones.
+func (m *Model) measureRows() {
+	m.lines = map[Node]string{}
+	m.tall = nil
+	for i := range m.nodes {
+		if h := m.measureRow(i); h > 1 {
+			m.tall = append(m.tall, tall{index: i, extra: h - 1})
+		}
+	}
+	m.countRowsAbove(0)
+}
+
+// measureRow returns the number of rows the node at the given index takes up.
+// The wrapped names can't be told apart without laying them out, so such nodes,
+// along with the search results, get rendered right away.
+func (m *Model) measureRow(i int) (height int) {
+	n := m.nodes[i]
+	if m.wrap || m.showingResults() || isNil(n) || m.mode == modeRename && isSelected(n) {
+		m.lines[n] = m.safeRender(n)
+		return lipgloss.Height(m.lines[n])
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			m.report(n, fmt.Errorf("rendering %T: %v", n, r))
+			m.lines[n] = m.brokenRow()
+			height = 1
+		}
+	}()
+	height = strings.Count(n.Name(), "\n") + 1
+	if description(n) != "" && !isPlaceholder(n) {
+		height++
+	}
+	return height
+}
+
+// tallAt returns the position of the first tall node at or after the given index.
+func (m Model) tallAt(i int) int {
+	return sort.Search(len(m.tall), func(k int) bool { return m.tall[k].index >= i })
+}
+
+// countRowsAbove recomputes the extra rows above the tall nodes, starting with the k-th one.
+func (m *Model) countRowsAbove(k int) {
+	for ; k < len(m.tall); k++ {
+		m.tall[k].above = 0
+		if k > 0 {
+			m.tall[k].above = m.tall[k-1].above + m.tall[k-1].extra
+		}
+	}
+}
+
+// setHeight records the number of rows the node at the given index takes up,
+// shifting the rows of the nodes below it.
+func (m *Model) setHeight(i, h int) {
+	k := m.tallAt(i)
+	switch found := k < len(m.tall) && m.tall[k].index == i; {
+	case found && h > 1:
+		m.tall[k].extra = h - 1
+	case found:
+		m.tall = slices.Delete(m.tall, k, k+1)
+	case h > 1:
+		m.tall = slices.Insert(m.tall, k, tall{index: i, extra: h - 1})
+	}
+	m.countRowsAbove(k)
+}
+
+// spliceHeights replaces the heights of the nodes in [from, to) with the ones of the given
+// number of nodes, of which the tall ones are given, shifting the nodes below them.
+func (m *Model) spliceHeights(from, to, count int, added []tall) {
+	start, end := m.tallAt(from), m.tallAt(to)
+	for k := end; k < len(m.tall); k++ {
+		m.tall[k].index += count - (to - from)
+	}
+	m.tall = slices.Replace(m.tall, start, end, added...)
+	m.countRowsAbove(start)
+}
+
+// totalRows returns the number of rows all of the nodes take up.
+func (m Model) totalRows() int {
+	if len(m.tall) == 0 {
+		return len(m.nodes)
+	}
+	last := m.tall[len(m.tall)-1]
+	return len(m.nodes) + last.above + last.extra
+}
+
+// window returns the range of the nodes which are rendered, the ones in the viewport
+// along with a page of them above and below it, or all of them if the height isn't known yet.
+func (m Model) window() (int, int) {
+	if m.view.Height <= 0 || len(m.nodes) == 0 {
+		return 0, len(m.nodes)
+	}
+	top := clamp(m.view.YOffset, 0, max(m.totalRows()-m.view.Height, 0))
+	return m.nodeAtRow(max(top-m.view.Height, 0)), min(m.nodeAtRow(top+2*m.view.Height)+1, len(m.nodes))
+}
+
+// setContent renders the nodes in and near the viewport, keeping the viewport within the rows.
+func (m *Model) setContent() {
+	m.materialize()
+	if m.view.YOffset > m.totalRows()-1 {
+		m.setYOffset(m.totalRows())
+	}
+}
+
+// materialize renders the nodes which have been scrolled into view since they were measured,
+// dropping the ones which have been scrolled far away.
+func (m *Model) materialize() {
+	from, to := m.window()
+	for i := from; i < to; i++ {
+		n := m.nodes[i]
+		if _, ok := m.lines[n]; ok {
+			continue
+		}
+		m.lines[n] = m.safeRender(n)
+		if h := lipgloss.Height(m.lines[n]); h != m.heightOf(i) {
+			// shifts the rows of the nodes below it, and with them the window
+			m.setHeight(i, h)
+			from, to = m.window()
+		}
+	}
+	if len(m.lines) > 2*(to-from) {
+		kept := make(map[Node]string, to-from)
+		for _, n := range m.nodes[from:to] {
+			kept[n] = m.lines[n]
+		}
+		m.lines = kept
+	}
+}
+
+// materialized reports whether all of the nodes in and near the viewport have been rendered.
+func (m Model) materialized() bool {
+	from, to := m.window()
+	for _, n := range m.nodes[from:to] {
+		if _, ok := m.lines[n]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// materializedCopy returns a copy of the model with the nodes in and near the viewport rendered,
+// leaving the rendered nodes of the model as they are, e.g. when it's scrolled outside of Update.
+func (m Model) materializedCopy() Model {
+	if m.materialized() {
+		return m
+	}
+	m.lines, m.tall = maps.Clone(m.lines), slices.Clone(m.tall)
+	m.materialize()
+	return m
+}
+
+// line returns the rendered node at the given index, rendering it if it hasn't been.
+func (m Model) line(i int) string {
+	if l, ok := m.lines[m.nodes[i]]; ok {
+		return l
+	}
+	return m.safeRender(m.nodes[i])
+}
+
+// viewportView renders the rows in the viewport, the given width wide.
+func (m Model) viewportView(width int) string {
+	rows := make([]string, 0, m.view.Height)
+	top := max(m.view.YOffset, 0)
+	for i := m.nodeAtRow(top); i >= 0 && i < len(m.nodes) && len(rows) < m.view.Height; i++ {
+		lines := strings.Split(m.line(i), "\n")
+		if skip := top - m.rowOf(i); skip > 0 {
+			// the first node is scrolled partially out of view
+			lines = lines[min(skip, len(lines)):]
+		}
+		rows = append(rows, lines...)
+	}
+	view := m.view
+	view.Width, view.YOffset = width, 0
+	view.SetContent(strings.Join(rows[:min(len(rows), max(m.view.Height, 0))], "\n"))
+	return view.View()
+}
+
+// setYOffset scrolls the viewport to the given row, keeping it within the rows.
+func (m *Model) setYOffset(n int) {
+	m.view.YOffset = clamp(n, 0, max(m.totalRows()-m.view.Height, 0))
+}
+
+// visibleRows returns the first and the last row in the viewport.
+func (m Model) visibleRows() (int, int) {
+	top := max(0, m.view.YOffset)
+	return top, clamp(m.view.YOffset+m.view.Height-1, top, m.totalRows()-1)
+}
+
+// rowOf returns the row the node at the given index starts at.
+func (m Model) rowOf(i int) int {
+	if i < 0 || i >= len(m.nodes) {
+		return i
+	}
+	if k := m.tallAt(i); k > 0 {
+		return i + m.tall[k-1].above + m.tall[k-1].extra
+	}
+	return i
+}
+
+// heightOf returns the number of rows the node at the given index takes up.
+func (m Model) heightOf(i int) int {
+	if k := m.tallAt(i); k < len(m.tall) && m.tall[k].index == i {
+		return m.tall[k].extra + 1
+	}
+	return 1
+}
+
+// nodeAtRow returns the index of the node rendered in the given row.
+func (m Model) nodeAtRow(row int) int {
+	// the last tall node starting at or above the row
+	k := sort.Search(len(m.tall), func(k int) bool { return m.tall[k].index+m.tall[k].above > row }) - 1
+	if k < 0 {
+		return row
+	}
+	t := m.tall[k]
+	if start := t.index + t.above; row <= start+t.extra {
+		return t.index
+	}
+	return row - t.above - t.extra
+}
+
+// spliceRows re-flattens the subtree of the node at the given index after it has been expanded
+// or collapsed, measuring and rendering only the rows of the node and its subtree, the rest of
+// them are kept as they are. Everything in the viewport gets re-rendered if the change shifts
+// the rest of the rows too, e.g. the widths of the aligned columns or of the gutter, and the
+// whole tree is refreshed if the rows depend on their positions, e.g. the stripes.
+func (m *Model) spliceRows(i int) {
+	if i < 0 || i >= len(m.nodes) || m.flattened != m.version || m.striping || m.showingResults() || len(m.ghosts) > 0 {
+		m.refresh()
+		return
+	}
+	n := m.nodes[i]
+	end := i + 1
+	for end < len(m.nodes) && isDescendant(m.nodes[end], n) {
+		end++
+	}
+	rows := m.subtreeRows(n)
+	gutter, prefixes, columns := m.gutterWidth(), m.prefixColumns, m.columnWidths
+
+	m.nodes = slices.Replace(m.nodes, i+1, end, rows...)
+	m.version++
+	m.flattened = m.version
+	delete(m.lines, n)
+	added := []tall{}
+	for j := i + 1; j <= i+len(rows); j++ {
+		delete(m.lines, m.nodes[j])
+		if h := m.measureRow(j); h > 1 {
+			added = append(added, tall{index: j, extra: h - 1})
+		}
+	}
+	m.spliceHeights(i+1, end, len(rows), added)
+
+	m.measurePrefixes()
+	m.measureColumns()
+	if m.gutterWidth() != gutter || !slices.Equal(m.prefixColumns, prefixes) || !slices.Equal(m.columnWidths, columns) {
+		m.rerender()
+		return
+	}
+	m.setContent()
+}
+
+// subtreeRows returns the rows shown below the node, the way flattenNodes would.
+func (m Model) subtreeRows(n Node) Nodes {
+	rows := Nodes{n}
+	if isCollapsible(n) && isExpanded(n) {
+		rows = append(rows, n.Children().flatten(m.less)...)
+	}
+	return m.withPlaceholders(m.withChunks(rows))[1:]
+}
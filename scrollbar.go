@@ -0,0 +1,68 @@
+package tree
+
+import (
+	"math"
+	"strings"
+)
+
+// Scrollbar holds the glyphs of the scrollbar on the right edge of the tree.
+type Scrollbar struct {
+	Track string
+	Thumb string
+}
+
+// DefaultScrollbar returns the default glyphs of the scrollbar.
+func DefaultScrollbar() Scrollbar {
+	return Scrollbar{
+		Track: "│",
+		Thumb: "┃",
+	}
+}
+
+// WithScrollbar shows the scrollbar, see SetScrollbar.
+func WithScrollbar() Option {
+	return func(m *Model) {
+		m.scrollbar = true
+	}
+}
+
+// SetScrollbar toggles the scrollbar on the right edge of the tree, whose thumb
+// reflects the ScrollPercent and the visible fraction of the rows.
+func (m *Model) SetScrollbar(on bool) {
+	m.scrollbar = on
+	// the width of the rows changes
+	m.rerender()
+}
+
+// scrollbarWidth returns the width of the scrollbar, 0 if it's hidden.
+func (m Model) scrollbarWidth() int {
+	if !m.scrollbar {
+		return 0
+	}
+	return 1
+}
+
+// thumb returns the first row and the number of rows of the scrollbar's thumb.
+func (m Model) thumb() (int, int) {
+	height, total := m.view.Height, m.totalRows()
+	if total <= height {
+		return 0, height
+	}
+	size := max(height*height/total, 1)
+	top := int(math.Round(m.ScrollPercent() * float64(height-size)))
+	return top, size
+}
+
+// scrollbarView renders the scrollbar as tall as the viewport.
+func (m Model) scrollbarView() string {
+	top, size := m.thumb()
+	rows := make([]string, m.view.Height)
+	for i := range rows {
+		if top <= i && i < top+size {
+			rows[i] = m.Styles.ScrollbarThumb.Render(m.Scrollbar.Thumb)
+		} else {
+			rows[i] = m.Styles.ScrollbarTrack.Render(m.Scrollbar.Track)
+		}
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,261 @@
+package tree
+
+import (
+	"sort"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const searchPrompt = "/"
+
+// search holds the state of the incremental search.
+type search struct {
+	query   string
+	matches Nodes
+	scores  []int
+	current int  // index into matches, -1 if the cursor isn't on a match
+	origin  Node // where the cursor was when the prompt was opened
+}
+
+// OpenSearch opens the search prompt. The matches are updated and jumped to
+// while typing, Enter keeps the query for cycling through the matches and
+// Esc returns the cursor to where it was.
+func (m *Model) OpenSearch() tea.Cmd {
+	m.mode = modeSearch
+	m.promptErr = nil
+	m.prompt.Prompt = m.searchPrompt()
+	m.prompt.Reset()
+	m.queries.rewind()
+	if len(m.nodes) > 0 {
+		m.search.origin = m.currentNode()
+	}
+	m.layout()
+	return m.prompt.Focus()
+}
+
+// closeSearch closes the search prompt, keeping the query.
+func (m *Model) closeSearch() {
+	m.mode = modeNormal
+	m.promptErr = nil
+	m.prompt.Blur()
+	m.search.origin = nil
+	m.layout()
+}
+
+func (m Model) updateSearch(msg tea.Msg) (Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(msg, m.KeyMap.Cancel):
+			origin := m.search.origin
+			m.closeSearch()
+			m.ClearSearch()
+			if origin != nil {
+				m.refreshAndSelect(origin)
+			}
+			return m, noop
+		case key.Matches(msg, m.KeyMap.Confirm):
+			m.queries.add(m.prompt.Value())
+			m.closeSearch()
+			return m, noop
+		case key.Matches(msg, m.KeyMap.PrevQuery), key.Matches(msg, m.KeyMap.NextQuery):
+			q, ok := m.queries.recall(key.Matches(msg, m.KeyMap.NextQuery), m.prompt.Value())
+			if !ok {
+				return m, noop
+			}
+			m.prompt.SetValue(q)
+			m.prompt.CursorEnd()
+			return m, m.incrementalSearch(q)
+		case key.Matches(msg, m.KeyMap.ToggleRegexp):
+			m.ToggleRegexp()
+			m.prompt.Prompt = m.searchPrompt()
+			return m, m.incrementalSearch(m.prompt.Value())
+		case key.Matches(msg, m.KeyMap.ToggleCase):
+			m.cycleCaseMode()
+			return m, m.incrementalSearch(m.prompt.Value())
+		}
+	}
+
+	var cmd tea.Cmd
+	query := m.prompt.Value()
+	m.prompt, cmd = m.prompt.Update(msg)
+	if q := m.prompt.Value(); q != query {
+		return m, tea.Batch(cmd, m.incrementalSearch(q))
+	}
+	return m, cmd
+}
+
+// incrementalSearch searches for the query, starting from where the cursor
+// was when the prompt was opened.
+// Malformed queries are reported in the prompt, keeping the matches of the last valid one.
+func (m *Model) incrementalSearch(query string) tea.Cmd {
+	if m.promptErr = m.validateQuery(query); m.promptErr != nil {
+		return noop
+	}
+	if m.search.origin != nil {
+		m.refreshAndSelect(m.search.origin)
+	}
+	return m.Search(query)
+}
+
+// Search finds all nodes matching the query, see SetMatcher and SetSearchTarget, and moves the cursor
+// to the first match at, or after, the cursor, expanding its ancestors if needed.
+// Empty query clears the search.
+func (m *Model) Search(query string) tea.Cmd {
+	if query == "" {
+		m.ClearSearch()
+		return noop
+	}
+	m.search.query = query
+	m.layout()
+	start := m.findMatches(query)
+	if len(m.search.matches) == 0 {
+		// dropping the highlights, or the results, of the previous query
+		m.refreshAndSelect(m.selectedNode())
+		return noop
+	}
+	if start == -1 {
+		// nothing after the cursor, wrapping around
+		start = 0
+	}
+	return m.gotoMatch(start)
+}
+
+// findMatches collects the matches of the query, returning the index of
+// the first one at, or after, the cursor, -1 if there is none.
+func (m *Model) findMatches(query string) int {
+	m.search.matches = nil
+	m.search.scores = nil
+	m.search.current = -1
+
+	// the matches are ordered as they'd be rendered, collapsed nodes included
+	all := m.roots().ordered(m.less)
+	cursor := -1
+	if len(m.nodes) > 0 {
+		cursor = all.index(m.currentNode())
+	}
+
+	start := -1
+	for i, n := range all {
+		match, ok := m.matchNode(query, n)
+		if !ok {
+			continue
+		}
+		if start == -1 && i >= cursor {
+			start = len(m.search.matches)
+		}
+		m.search.matches = append(m.search.matches, n)
+		m.search.scores = append(m.search.scores, match.Score)
+	}
+	return start
+}
+
+// ClearSearch drops the current query and its matches.
+func (m *Model) ClearSearch() {
+	current := m.selectedNode()
+	origin := m.search.origin
+	m.search = search{current: -1, origin: origin}
+	m.layout()
+	// dropping the highlights, or going back from the results to the tree
+	m.refreshAndSelect(current)
+}
+
+// SearchQuery returns the currently active search query.
+func (m Model) SearchQuery() string {
+	return m.search.query
+}
+
+// Matches returns all of the nodes matching the current search query,
+// in the order they are rendered in.
+func (m Model) Matches() Nodes {
+	return m.search.matches
+}
+
+// RankedMatches returns the same nodes as Matches, but ordered by their score,
+// the best match first. Equally good matches keep their order.
+func (m Model) RankedMatches() Nodes {
+	idx := make([]int, len(m.search.matches))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		return m.search.scores[idx[i]] > m.search.scores[idx[j]]
+	})
+	ranked := make(Nodes, len(idx))
+	for i, j := range idx {
+		ranked[i] = m.search.matches[j]
+	}
+	return ranked
+}
+
+// NextMatch moves the cursor to the next match, wrapping around at the end.
+func (m *Model) NextMatch() tea.Cmd {
+	return m.cycleMatch(1)
+}
+
+// PrevMatch moves the cursor to the previous match, wrapping around at the start.
+func (m *Model) PrevMatch() tea.Cmd {
+	return m.cycleMatch(-1)
+}
+
+func (m *Model) cycleMatch(dir int) tea.Cmd {
+	count := len(m.search.matches)
+	if count == 0 {
+		return noop
+	}
+	next := m.search.current + dir
+	if m.search.current == -1 && dir < 0 {
+		next = count - 1
+	}
+	return m.gotoMatch((next%count + count) % count)
+}
+
+// gotoMatch reveals the i-th match and moves the cursor onto it.
+func (m *Model) gotoMatch(i int) tea.Cmd {
+	m.search.current = i
+	if m.showingResults() {
+		// the results are listed as they are, nothing to expand
+		m.refreshAndSelect(m.search.matches[i])
+		return noop
+	}
+	m.reveal(m.search.matches[i])
+	return noop
+}
+
+// RevealNode expands all of the collapsed ancestors of the node, moves the cursor
+// onto it and scrolls it into view. It reports whether the node was found, i.e.
+// it's a part of the tree and neither it nor any of its ancestors is hidden.
+func (m *Model) RevealNode(n Node) bool {
+	if !m.revealable(n) {
+		return false
+	}
+	m.saveUndo()
+	return m.reveal(n)
+}
+
+// revealable reports whether the node is a part of the tree, and neither it nor any of its ancestors is hidden.
+func (m Model) revealable(n Node) bool {
+	if isNil(n) || isHidden(n) {
+		return false
+	}
+	top := n
+	for _, a := range Ancestors(n) {
+		if isHidden(a) {
+			return false
+		}
+		top = a
+	}
+	return m.tree.index(top) != -1
+}
+
+// reveal expands all of the collapsed ancestors of the node and moves the
+// cursor onto it, reporting whether the node is now visible.
+func (m *Model) reveal(n Node) bool {
+	m.showChunkOf(n)
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		m.setCollapsed(p, false)
+		m.showChunkOf(p)
+	}
+	m.refreshAndSelect(n)
+	return len(m.nodes) > 0 && m.currentNode() == n
+}
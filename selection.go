@@ -0,0 +1,39 @@
+package tree
+
+import "github.com/charmbracelet/lipgloss"
+
+// WithFullWidthSelection extends the selected style across the whole row, see SetFullWidthSelection.
+func WithFullWidthSelection() Option {
+	return func(m *Model) {
+		m.fullWidthSelection = true
+	}
+}
+
+// SetFullWidthSelection toggles extending the style of the row under the cursor
+// across the whole width of the tree, the prefix and the tree symbols included,
+// like the list and table bubbles do, instead of styling only the name.
+func (m *Model) SetFullWidthSelection(on bool) {
+	m.fullWidthSelection = on
+	m.rerenderNode(m.cursor)
+}
+
+// selectionBar reports whether the row of the node is rendered as a full width bar.
+func (m Model) selectionBar(n Node) bool {
+	return m.fullWidthSelection && isSelected(n) && m.Width() > 0
+}
+
+// barRow pads the row to the full width of the tree in the given style.
+func (m Model) barRow(style lipgloss.Style, row string) string {
+	if row == "" {
+		return ""
+	}
+	return row + pad(style, m.contentWidth()-textWidth(row))
+}
+
+// restyle renders the already styled parts of the row in the given style instead.
+func restyle(style lipgloss.Style, s string) string {
+	if s == "" {
+		return ""
+	}
+	return style.Render(stripANSI(s))
+}
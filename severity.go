@@ -0,0 +1,90 @@
+package tree
+
+import "github.com/charmbracelet/lipgloss"
+
+// Severity ranks how problematic a node is, e.g. the health of a pod.
+type Severity int
+
+const (
+	// SeverityNone is reported by the nodes which don't implement SeverityReporter
+	SeverityNone Severity = iota
+	// SeverityOK is rendered as any other node
+	SeverityOK
+	// SeverityWarning is rendered with the Warning style
+	SeverityWarning
+	// SeverityError is rendered with the Error style, it's the worst one
+	SeverityError
+)
+
+// SeverityReporter is an optional interface for nodes with a severity,
+// rendered with the Warning or Error style.
+type SeverityReporter interface {
+	Severity() Severity
+}
+
+// WithSeverityRollup enables the severity roll-up, see SetSeverityRollup.
+func WithSeverityRollup() Option {
+	return func(m *Model) {
+		m.severityRollup = true
+	}
+}
+
+// SetSeverityRollup toggles rendering the ancestors with the style of the
+// worst severity of their descendants, so the collapsed branches still signal
+// the problems inside them.
+func (m *Model) SetSeverityRollup(on bool) {
+	m.severityRollup = on
+	m.rollUpSeverities()
+	m.rerender()
+}
+
+// Severity returns the severity of the node, rolled up from its descendants if enabled.
+func (m Model) Severity(n Node) Severity {
+	if m.severityRollup {
+		if s, ok := m.severities[n]; ok {
+			return s
+		}
+	}
+	return ownSeverity(n)
+}
+
+func ownSeverity(n Node) Severity {
+	if r, ok := n.(SeverityReporter); ok {
+		return r.Severity()
+	}
+	return SeverityNone
+}
+
+// rollUpSeverities aggregates the worst severity of every node and its
+// descendants, hidden ones excluded, in a single pass.
+func (m *Model) rollUpSeverities() {
+	m.severities = map[Node]Severity{}
+	if !m.severityRollup {
+		return
+	}
+	order := Nodes{}
+	traverse(m.tree.visible(), 0, func(n Node, _ int, _ Nodes, _ int) Nodes {
+		order = append(order, n)
+		return n.Children().visible()
+	})
+	// the descendants come after their ancestors, so they're done by the time the ancestors are
+	for i := len(order) - 1; i >= 0; i-- {
+		n := order[i]
+		worst := ownSeverity(n)
+		for _, child := range n.Children().visible() {
+			worst = max(worst, m.severities[child])
+		}
+		m.severities[n] = worst
+	}
+}
+
+// severityStyle returns the style for the severity, and whether there is one.
+func (m Model) severityStyle(n Node) (lipgloss.Style, bool) {
+	switch m.Severity(n) {
+	case SeverityError:
+		return m.Styles.Error, true
+	case SeverityWarning:
+		return m.Styles.Warning, true
+	}
+	return lipgloss.Style{}, false
+}
@@ -0,0 +1,117 @@
+package tree
+
+// ValueNode is a ready-made Node holding a name, an optional prefix, its children and
+// a value of the application, e.g. the file or the resource the node stands for,
+// see ValueOf. The children can be changed after the model has been created,
+// see ChildSetter and ChildSwapper.
+type ValueNode[T any] struct {
+	name     string
+	prefix   string
+	value    T
+	parent   *ValueNode[T]
+	children []*ValueNode[T]
+	state    NodeState
+}
+
+// SimpleNode is a ValueNode without a value, for the trees which don't need
+// a Node implementation of their own, see Builder.
+type SimpleNode = ValueNode[struct{}]
+
+// to ensure it implements the interfaces
+var (
+	_ Node         = (*SimpleNode)(nil)
+	_ ChildSetter  = (*SimpleNode)(nil)
+	_ ChildSwapper = (*SimpleNode)(nil)
+)
+
+// NewSimpleNode creates a node with the given children, it's a leaf if there are none.
+func NewSimpleNode(name string, children ...*SimpleNode) *SimpleNode {
+	return NewValueNode(name, struct{}{}, children...)
+}
+
+// NewValueNode creates a node holding the value, with the given children, it's a leaf if there are none.
+func NewValueNode[T any](name string, value T, children ...*ValueNode[T]) *ValueNode[T] {
+	n := &ValueNode[T]{name: name, value: value}
+	n.Append(children...)
+	return n
+}
+
+// ValueOf returns the value held by the node, e.g. the one returned by SelectedNode,
+// and whether it is a ValueNode holding a value of the type.
+func ValueOf[T any](n Node) (T, bool) {
+	if vn, ok := n.(*ValueNode[T]); ok {
+		return vn.value, true
+	}
+	var zero T
+	return zero, false
+}
+
+// Value returns the value held by the node.
+func (n *ValueNode[T]) Value() T {
+	return n.value
+}
+
+// SetValue sets the value held by the node.
+func (n *ValueNode[T]) SetValue(value T) {
+	n.value = value
+}
+
+// Append appends the children to the node, setting itself as their parent,
+// which makes the node collapsible.
+// Use InsertNode instead once the node is a part of a model.
+func (n *ValueNode[T]) Append(children ...*ValueNode[T]) {
+	for _, c := range children {
+		c.parent = n
+		n.children = append(n.children, c)
+	}
+	if len(n.children) > 0 {
+		n.state |= NodeCollapsible
+	}
+}
+
+// SetName sets the name of the node, the model has to be refreshed for it to be shown, e.g. by SetNodes.
+func (n *ValueNode[T]) SetName(name string) {
+	n.name = name
+}
+
+// SetPrefix sets the prefix of the node, see Node.
+func (n *ValueNode[T]) SetPrefix(prefix string) {
+	n.prefix = prefix
+}
+
+func (n *ValueNode[T]) Name() string   { return n.name }
+func (n *ValueNode[T]) Prefix() string { return n.prefix }
+
+func (n *ValueNode[T]) Parent() Node {
+	if n.parent == nil {
+		return nil
+	}
+	return n.parent
+}
+
+func (n *ValueNode[T]) Children() Nodes {
+	ns := make(Nodes, len(n.children))
+	for i, c := range n.children {
+		ns[i] = c
+	}
+	return ns
+}
+
+func (n *ValueNode[T]) State() NodeState      { return n.state }
+func (n *ValueNode[T]) SetState(st NodeState) { n.state = st }
+
+// SetChildren replaces the children of the node, setting itself as their parent.
+// The children which aren't ValueNodes of the same type are dropped.
+func (n *ValueNode[T]) SetChildren(ns Nodes) {
+	n.children = make([]*ValueNode[T], 0, len(ns))
+	for _, c := range ns {
+		if c, ok := c.(*ValueNode[T]); ok {
+			c.parent = n
+			n.children = append(n.children, c)
+		}
+	}
+}
+
+func (n *ValueNode[T]) SwapChildren(i, j int) {
+	n.children[i], n.children[j] = n.children[j], n.children[i]
+}
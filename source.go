@@ -0,0 +1,81 @@
+package tree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// Patch is a change to the tree sent by a Source, one of AddNodesMsg, RemoveNodeMsg,
+// SetStateMsg, or RefreshedMsg for replacing all of the nodes.
+type Patch interface {
+	patch()
+}
+
+func (AddNodesMsg) patch()   {}
+func (RemoveNodeMsg) patch() {}
+func (SetStateMsg) patch()   {}
+func (RefreshedMsg) patch()  {}
+
+// Source is an external producer of the nodes, e.g. a filesystem watcher, see Subscribe.
+type Source interface {
+	// Initial should return the nodes the tree starts with.
+	Initial() Nodes
+	// Updates should return the channel the changes made to the nodes since are sent to,
+	// closing it stops the updates.
+	Updates() <-chan Patch
+}
+
+// patchMsg carries a patch received from the source, the patches of the replaced sources are ignored.
+type patchMsg struct {
+	id     int
+	patch  Patch
+	closed bool
+}
+
+// Subscribe replaces the nodes with the initial ones of the source, see SetNodes, and
+// returns the command applying the patches from its updates as they arrive, so the
+// nodes never have to be touched outside of Update.
+// Subscribing to another source, or nil, stops applying the patches of the previous one.
+func (m *Model) Subscribe(s Source) tea.Cmd {
+	m.subscription.id++
+	m.subscription.updates = nil
+	if s == nil {
+		return noop
+	}
+	m.subscription.updates = s.Updates()
+	m.SetNodes(s.Initial())
+	return tea.Batch(m.receivePatch(), m.hydrateVisible())
+}
+
+// subscription holds the updates of the source given to Subscribe.
+type subscription struct {
+	id      int
+	updates <-chan Patch
+}
+
+// receivePatch returns the command waiting for the next patch of the source.
+func (m Model) receivePatch() tea.Cmd {
+	id, updates := m.subscription.id, m.subscription.updates
+	return func() tea.Msg {
+		p, ok := <-updates
+		return patchMsg{id: id, patch: p, closed: !ok}
+	}
+}
+
+// applyPatch applies the patch and waits for the next one.
+func (m *Model) applyPatch(msg patchMsg) tea.Cmd {
+	if msg.id != m.subscription.id || m.subscription.updates == nil {
+		return noop
+	}
+	if msg.closed {
+		m.subscription.updates = nil
+		return noop
+	}
+	switch p := msg.patch.(type) {
+	case nil:
+	case RefreshedMsg:
+		if p.Nodes != nil {
+			m.SetNodes(p.Nodes)
+		}
+	default:
+		m.applyMutation(p)
+	}
+	return tea.Batch(m.receivePatch(), m.hydrateVisible())
+}
@@ -0,0 +1,94 @@
+package tree
+
+import "strings"
+
+// SetStatusLine toggles the status line below the tree, shown while there is
+// an active search query, with the position of the cursor among the matches,
+// or a status set with SetStatus. It's shown by default.
+func (m *Model) SetStatusLine(on bool) {
+	m.hideStatus = !on
+	m.layout()
+}
+
+// SetStatus sets the text shown in the status line, in front of the position among
+// the matches, e.g. the Summary. The status line is shown as long as it's not empty.
+func (m *Model) SetStatus(status string) {
+	m.status = status
+	m.layout()
+}
+
+// SetTitle sets the title shown in a bar above the tree with the Title style, e.g. the root path.
+// Empty title hides the bar.
+func (m *Model) SetTitle(title string) {
+	m.titleBar = title
+	m.layout()
+}
+
+// Summary returns a ready-made status, e.g. "142 items · 37% · filter: *.go".
+func (m Model) Summary() string {
+	parts := []string{m.tr(TextItemCount, m.itemCount()), m.tr(TextScrollPercent, int(m.ScrollPercent()*100))}
+	if m.filterQuery != "" {
+		parts = append(parts, m.tr(TextFilterSummary, m.filterQuery))
+	}
+	return strings.Join(parts, statusSeparator)
+}
+
+const statusSeparator = " · "
+
+// itemCount returns the number of the visible nodes, leaving out the rows which
+// don't belong to an actual node, e.g. the loading and the "load more" rows.
+func (m Model) itemCount() int {
+	count := 0
+	for _, n := range m.nodes {
+		if !isPlaceholder(n) {
+			count++
+		}
+	}
+	return count
+}
+
+// statusShown reports whether the status line takes up a row.
+func (m Model) statusShown() bool {
+	return !m.hideStatus && (m.search.query != "" || m.status != "")
+}
+
+// titleShown reports whether the title bar takes up a row.
+func (m Model) titleShown() bool {
+	return m.titleBar != ""
+}
+
+// statusView renders the status line, e.g. "142 items · match 3/17".
+func (m Model) statusView() string {
+	parts := []string{}
+	if m.status != "" {
+		parts = append(parts, m.status)
+	}
+	if m.search.query != "" {
+		parts = append(parts, m.matchesStatus())
+	}
+	return m.Styles.StatusLine.Render(m.fitWidth(strings.Join(parts, statusSeparator)))
+}
+
+// matchesStatus returns the position of the cursor among the matches, e.g. "match 3/17".
+func (m Model) matchesStatus() string {
+	count := len(m.search.matches)
+	if i := m.search.matches.index(m.currentNode()); i != -1 {
+		return m.tr(TextMatchPosition, i+1, count)
+	} else if count > 0 {
+		return m.tr(TextMatchCount, count)
+	}
+	return m.tr(TextNoMatches)
+}
+
+// titleView renders the title bar.
+func (m Model) titleView() string {
+	return m.Styles.Title.Render(m.fitWidth(m.titleBar))
+}
+
+// fitWidth truncates the text to the width of the tree, if it's known.
+func (m Model) fitWidth(s string) string {
+	if m.Width() <= 0 {
+		return s
+	}
+	return m.Truncation.shorten(s, m.Width())
+}
@@ -0,0 +1,98 @@
+package tree
+
+import "strings"
+
+// WithStickyAncestors keeps the ancestors of the topmost node in view, see SetStickyAncestors.
+func WithStickyAncestors(limit int) Option {
+	return func(m *Model) {
+		m.stickyLimit = limit
+	}
+}
+
+// SetStickyAncestors keeps up to limit ancestors of the topmost visible node
+// at the top of the viewport, like the sticky scroll of the editors, so the context
+// of a deep subtree is never lost while scrolling through it. They cover the
+// first rows of the viewport. Zero turns it off.
+func (m *Model) SetStickyAncestors(limit int) {
+	m.stickyLimit = limit
+	m.scrollToCursor()
+}
+
+// sticky returns the indices of the nodes stuck at the top of the viewport,
+// the outermost ancestor first.
+func (m Model) sticky() []int {
+	if m.stickyLimit <= 0 || len(m.nodes) == 0 || m.showingResults() {
+		return nil
+	}
+	stuck := []int{}
+	for {
+		// the ancestors scrolled out of view, or covered by the ones already stuck
+		covered := m.view.YOffset + len(stuck)
+		i := m.nodeAtRow(covered)
+		if i < 0 || i >= len(m.nodes) {
+			return stuck
+		}
+		ancestors := []int{}
+		for _, j := range m.ancestorRows(i) {
+			if m.rowOf(j) < covered {
+				ancestors = append(ancestors, j)
+			}
+		}
+		ancestors = ancestors[:min(len(ancestors), m.stickyLimit)]
+		if len(ancestors) <= len(stuck) {
+			return stuck
+		}
+		stuck = ancestors
+	}
+}
+
+// ancestorRows returns the indices of the ancestors of the node at the given index, the outermost
+// first. The nodes are flattened depth first, so they're found by walking backwards from it.
+func (m Model) ancestorRows(i int) []int {
+	res := []int{}
+	p := m.nodes[i].Parent()
+	for j := i - 1; j >= 0 && p != nil; j-- {
+		if m.nodes[j] == p {
+			res = append([]int{j}, res...)
+			p = p.Parent()
+		}
+	}
+	return res
+}
+
+// coveredBySticky reports whether the node at the given index is hidden under the sticky ancestors.
+func (m Model) coveredBySticky(i int) bool {
+	stuck := m.sticky()
+	for _, j := range stuck {
+		if i == j {
+			return false
+		}
+	}
+	return m.rowOf(i) < m.view.YOffset+len(stuck)
+}
+
+// stickyRow returns the row of the viewport the node is stuck in, or -1 if it's not stuck.
+func (m Model) stickyRow(i int) int {
+	for k, j := range m.sticky() {
+		if i == j {
+			return k
+		}
+	}
+	return -1
+}
+
+// withSticky renders the sticky ancestors over the first rows of the rendered viewport.
+func (m Model) withSticky(view string) string {
+	stuck := m.sticky()
+	if len(stuck) == 0 {
+		return view
+	}
+	rows := strings.Split(view, "\n")
+	for k, i := range stuck {
+		if k < len(rows) {
+			first, _, _ := strings.Cut(m.line(i), "\n")
+			rows[k] = m.Styles.Sticky.Render(first)
+		}
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,27 @@
+package tree
+
+// WithStriping renders every other row with the AltLine style, see SetStriping.
+func WithStriping() Option {
+	return func(m *Model) {
+		m.striping = true
+	}
+}
+
+// SetStriping toggles rendering every other visible row with the AltLine style,
+// which helps following the wide rows. Rows with a style of their own keep it.
+func (m *Model) SetStriping(on bool) {
+	m.striping = on
+	m.stripeRows()
+	m.rerender()
+}
+
+// stripeRows marks every other visible row, starting with the second one.
+func (m *Model) stripeRows() {
+	m.stripes = map[Node]bool{}
+	if !m.striping {
+		return
+	}
+	for i := 1; i < len(m.nodes); i += 2 {
+		m.stripes[m.nodes[i]] = true
+	}
+}
@@ -0,0 +1,18 @@
+package tree
+
+// Suffixer is an optional interface for nodes with metadata rendered right-aligned
+// at the end of the row with the Suffix style, e.g. the file sizes, unlike the Prefix.
+// The name is truncated to make room for it.
+type Suffixer interface {
+	Suffix() string
+}
+
+// renderSuffix returns the styled suffix of the node preceded by a space, empty if it has none.
+func (m Model) renderSuffix(n Node) string {
+	s, ok := n.(Suffixer)
+	if !ok || s.Suffix() == "" {
+		return ""
+	}
+	style := m.nodeStyle(n)
+	return pad(style, 1) + m.Styles.Suffix.Copy().Inherit(style).Render(s.Suffix())
+}
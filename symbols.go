@@ -1,25 +1,92 @@
 package tree
 
-import "unicode/utf8"
+import (
+	"errors"
+	"strings"
 
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Symbols are the glyphs the tree is drawn with, any runes can be used.
+// The glyphs of different widths are padded to the widest one, see Normalized.
 type Symbols struct {
-	Connector  string
-	Starter    string
+	// Connector is the vertical line connecting the siblings across their descendants, e.g. "│"
+	Connector string
+	// Starter is the branch in front of every node but the last one among its siblings, e.g. "├─"
+	Starter string
+	// Terminator is the corner in front of the last node among its siblings, e.g. "└─"
 	Terminator string
+	// Horizontal extends the Starter and the Terminator when they're narrower than the rest, e.g. "─"
 	Horizontal string
+	// Padding fills the columns where there is nothing to connect, a space if empty
+	Padding string
+}
+
+// ErrMultilineSymbol is returned by Symbols.Validate for glyphs spanning more than a single row.
+var ErrMultilineSymbol = errors.New("tree: symbols must fit in a single row")
+
+// Validate reports whether the symbols can be drawn.
+func (s Symbols) Validate() error {
+	for _, glyph := range []string{s.Connector, s.Starter, s.Terminator, s.Horizontal, s.Padding} {
+		if strings.ContainsAny(glyph, "\r\n") {
+			return ErrMultilineSymbol
+		}
+	}
+	return nil
+}
+
+// Normalized returns the symbols with all of the glyphs as wide as the widest one,
+// the Starter and the Terminator are extended with the Horizontal, the rest with spaces.
+func (s Symbols) Normalized() Symbols {
+	w := width(s) - 1
+	line := " "
+	if lipgloss.Width(s.Horizontal) == 1 {
+		line = s.Horizontal
+	}
+	if s.Padding == "" {
+		s.Padding = " "
+	}
+	s.Connector = extend(s.Connector, " ", w)
+	s.Starter = extend(s.Starter, line, w)
+	s.Terminator = extend(s.Terminator, line, w)
+	s.Padding = extend(s.Padding, " ", w)
+	return s
+}
+
+// extend appends the filler to the glyph until it's w columns wide.
+func extend(glyph, filler string, w int) string {
+	if missing := w - lipgloss.Width(glyph); missing > 0 {
+		return glyph + strings.Repeat(filler, missing)
+	}
+	return glyph
+}
+
+// SetSymbols validates and normalizes the symbols before using them for drawing the tree.
+func (m *Model) SetSymbols(s Symbols) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	m.Symbols = s.Normalized()
+	m.rerender()
+	return nil
 }
 
+// width returns the width of a single level of the tree, the widest glyph and a separating column.
 func width(s Symbols) int {
-	co := utf8.RuneCount([]byte(s.Connector))
-	st := utf8.RuneCount([]byte(s.Starter))
-	te := utf8.RuneCount([]byte(s.Terminator))
-	return max(max(co, st), te) + 1
+	w := 0
+	for _, glyph := range []string{s.Connector, s.Starter, s.Terminator, s.Padding} {
+		w = max(w, lipgloss.Width(glyph))
+	}
+	return w + 1
 }
 
 // Padding is expected to output a whitespace, or equivalent, used when two nodes
 // at the same level are not children to the same parent.
 func Padding(style DepthStyler, s Symbols, depth int) string {
-	return draw(style, " ", width(s), depth)
+	if s.Padding == "" {
+		return draw(style, " ", width(s), depth)
+	}
+	return draw(style, s.Padding, width(s), depth)
 }
 
 // RenderTerminator is expected to output a terminator marker used for the last node in a list of nodes.
@@ -74,6 +141,12 @@ var (
 		Terminator: "╵",
 	}
 
+	asciiSymbols = Symbols{
+		Starter:    "+--",
+		Connector:  "|  ",
+		Terminator: "`--",
+	}
+
 	thickEdgeSymbols = Symbols{
 		Starter:    "╻",
 		Connector:  "┃",
@@ -110,3 +183,9 @@ func NormalEdgeSymbols() Symbols {
 func ThickEdgeSymbols() Symbols {
 	return thickEdgeSymbols
 }
+
+// ASCIISymbols returns a symbols without any box-drawing glyphs, for the
+// serial consoles, CI logs and terminals which can't render them.
+func ASCIISymbols() Symbols {
+	return asciiSymbols
+}
@@ -0,0 +1,122 @@
+package tree
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// NodeColumns is an optional interface for nodes with values rendered in the
+// columns right of the tree, see SetColumns.
+type NodeColumns interface {
+	Columns() []string
+}
+
+// Column is a column of values rendered right of the tree, e.g. the memory
+// usage in a process viewer or the size in a disk usage analyzer.
+type Column struct {
+	Title string
+	// Width of the column, zero fits the widest of the title and the values of the visible nodes
+	Width int
+	Align lipgloss.Position
+}
+
+// WithColumns turns the tree into a tree-table, see SetColumns.
+func WithColumns(title string, columns ...Column) Option {
+	return func(m *Model) {
+		m.title, m.columns = title, columns
+	}
+}
+
+// SetColumns turns the tree into a tree-table, with the tree in the first column
+// and the values of the nodes implementing NodeColumns in the aligned columns right of it.
+// The header row with the given title of the tree column and the titles of the columns
+// is rendered with the Header style above the tree, and stays in place while scrolling.
+// No columns turn it back into a plain tree.
+func (m *Model) SetColumns(title string, columns ...Column) {
+	m.title, m.columns = title, columns
+	m.layout()
+	m.measureColumns()
+	m.rerender()
+}
+
+// headerShown reports whether the header row of the columns is shown.
+func (m Model) headerShown() bool {
+	return len(m.columns) > 0
+}
+
+// measureColumns computes the widths of the columns, fitting the ones without a width
+// to the titles and the values of the visible nodes.
+func (m *Model) measureColumns() {
+	m.columnWidths = make([]int, len(m.columns))
+	for i, c := range m.columns {
+		m.columnWidths[i] = c.Width
+		if c.Width > 0 {
+			continue
+		}
+		m.columnWidths[i] = textWidth(c.Title)
+		for _, n := range m.nodes {
+			if values := columnValues(n); i < len(values) {
+				m.columnWidths[i] = max(m.columnWidths[i], textWidth(values[i]))
+			}
+		}
+	}
+}
+
+// columnsWidth returns the width of all of the columns, including the spaces before them.
+func (m Model) columnsWidth() int {
+	w := 0
+	for _, cw := range m.columnWidths {
+		w += cw + 1
+	}
+	return w
+}
+
+// columnValues returns the values of the node for the columns, empty if it has none.
+func columnValues(n Node) []string {
+	if c, ok := n.(NodeColumns); ok {
+		return c.Columns()
+	}
+	return nil
+}
+
+// columnsCells renders the values of the node aligned into the columns.
+func (m Model) columnsCells(n Node) string {
+	if len(m.columnWidths) == 0 {
+		return ""
+	}
+	values := columnValues(n)
+	cells := make([]string, len(m.columnWidths))
+	for i := range m.columnWidths {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		cells[i] = m.renderCell(m.nodeStyle(n), value, i)
+	}
+	return strings.Join(cells, "")
+}
+
+// renderCell renders the text in the given style, aligned within the i-th column,
+// with a space separating it from the previous one.
+func (m Model) renderCell(style lipgloss.Style, text string, i int) string {
+	w := m.columnWidths[i]
+	text = m.Truncation.shorten(text, w)
+	padding := max(w-textWidth(text), 0)
+	left := int(float64(padding) * float64(m.columns[i].Align))
+	return pad(style, 1+left) + style.Render(text) + pad(style, padding-left)
+}
+
+// headerView renders the header row, aligned with the rows of the tree.
+func (m Model) headerView() string {
+	style := m.Styles.Header
+	b := strings.Builder{}
+	b.WriteString(strings.Repeat(" ", m.gutterWidth()))
+	// leaving the last column of the tree empty, like the rows do
+	title := m.Truncation.shorten(m.title, max(m.rowWidth()-1, 0))
+	b.WriteString(style.Render(title) + pad(style, m.rowWidth()-1-textWidth(title)))
+	for i, c := range m.columns {
+		b.WriteString(m.renderCell(style, c.Title, i))
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package tree
+
+// SearchTarget selects which texts of a node are matched by the search and
+// filter queries, the targets can be combined, e.g. SearchName | SearchPrefix.
+type SearchTarget int
+
+const (
+	// SearchName matches the Name of the node
+	SearchName SearchTarget = 1 << iota
+	// SearchPrefix matches the Prefix of the node, e.g. its permissions or owner
+	SearchPrefix
+	// SearchText matches the SearchText of the nodes implementing Searchable
+	SearchText
+)
+
+// Searchable is an optional interface for nodes with more to search through
+// than their name, e.g. tags or descriptions, see SearchText.
+type Searchable interface {
+	SearchText() string
+}
+
+// SetSearchTarget sets which texts of the nodes are matched by the search and
+// filter queries, SearchName by default.
+func (m *Model) SetSearchTarget(t SearchTarget) {
+	m.searchTarget = t
+}
+
+// searchTexts returns the texts of the node selected by the search target.
+func (m Model) searchTexts(n Node) []string {
+	texts := []string{}
+	if m.searchTarget&SearchName != 0 {
+		texts = append(texts, stripANSI(n.Name()))
+	}
+	if m.searchTarget&SearchPrefix != 0 {
+		texts = append(texts, stripANSI(n.Prefix()))
+	}
+	if s, ok := n.(Searchable); ok && m.searchTarget&SearchText != 0 {
+		texts = append(texts, s.SearchText())
+	}
+	return texts
+}
+
+// matchNode matches the query against the texts of the node selected by the
+// search target, returning the best of the matches.
+func (m Model) matchNode(query string, n Node) (Match, bool) {
+	best, found := Match{}, false
+	for _, text := range m.searchTexts(n) {
+		match, ok := m.match(query, text)
+		if ok && (!found || match.Score > best.Score) {
+			best, found = match, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,108 @@
+package tree
+
+import "github.com/charmbracelet/lipgloss"
+
+// WithStyles sets the styles of the tree, e.g. one of the presets.
+func WithStyles(s Styles) Option {
+	return func(m *Model) {
+		m.Styles = s
+	}
+}
+
+// MonochromeStyles returns a preset without any colors, relying on the text attributes alone,
+// e.g. for terminals without color support or NO_COLOR users.
+func MonochromeStyles() Styles {
+	s := DefaultStyles()
+	s.AltLine = defaultStyle
+	s.Activatable = defaultStyle.Underline(true)
+	s.Warning = defaultStyle.Bold(true)
+	s.Error = defaultStyle.Bold(true).Italic(true)
+	s.Added = defaultStyle.Bold(true)
+	s.Changed = defaultStyle.Italic(true)
+	s.Removed = defaultStyle.Strikethrough(true)
+	return s
+}
+
+// HighContrastStyles returns a preset maximizing the contrast, without any faint
+// elements, adapting to the light and dark terminal backgrounds.
+func HighContrastStyles() Styles {
+	fg := lipgloss.AdaptiveColor{Light: "0", Dark: "15"}
+	bg := lipgloss.AdaptiveColor{Light: "15", Dark: "0"}
+
+	s := DefaultStyles()
+	s.AltLine = defaultStyle
+	s.Selected = defaultStyle.Foreground(bg).Background(fg).Bold(true)
+	s.Marked = defaultStyle.Bold(true).Underline(true)
+	s.SelectedMarked = s.Selected.Copy().Inherit(s.Marked)
+	s.MatchHighlight = defaultStyle.Foreground(lipgloss.Color("0")).Background(lipgloss.AdaptiveColor{Light: "3", Dark: "11"})
+	s.Activatable = defaultStyle.Foreground(lipgloss.AdaptiveColor{Light: "4", Dark: "14"}).Bold(true)
+	s.Warning = defaultStyle.Foreground(lipgloss.AdaptiveColor{Light: "3", Dark: "11"}).Bold(true)
+	s.Error = defaultStyle.Foreground(lipgloss.AdaptiveColor{Light: "1", Dark: "9"}).Bold(true)
+	for _, muted := range []*lipgloss.Style{&s.FilterBar, &s.Placeholder, &s.EmptyTree, &s.StatusLine, &s.LineNumber, &s.ChildCount, &s.Description, &s.Suffix, &s.ScrollbarTrack} {
+		*muted = defaultStyle.Foreground(fg)
+	}
+	return s
+}
+
+// DraculaStyles returns a preset in the colors of the Dracula theme, or its light variant Alucard.
+func DraculaStyles() Styles {
+	return palette{
+		Accent:    lipgloss.AdaptiveColor{Light: "#644AC9", Dark: "#BD93F9"},
+		Selection: lipgloss.AdaptiveColor{Light: "#CFCFDE", Dark: "#44475A"},
+		Match:     lipgloss.AdaptiveColor{Light: "#A3144D", Dark: "#FF79C6"},
+		Warning:   lipgloss.AdaptiveColor{Light: "#A34D14", Dark: "#FFB86C"},
+		Error:     lipgloss.AdaptiveColor{Light: "#CB3A2A", Dark: "#FF5555"},
+		Muted:     lipgloss.AdaptiveColor{Light: "#635D97", Dark: "#6272A4"},
+		Stripe:    lipgloss.AdaptiveColor{Light: "#F5F5EF", Dark: "#21222C"},
+	}.styles()
+}
+
+// NordStyles returns a preset in the colors of the Nord theme.
+func NordStyles() Styles {
+	return palette{
+		Accent:    lipgloss.AdaptiveColor{Light: "#5E81AC", Dark: "#88C0D0"},
+		Selection: lipgloss.AdaptiveColor{Light: "#D8DEE9", Dark: "#434C5E"},
+		Match:     lipgloss.AdaptiveColor{Light: "#B48EAD", Dark: "#EBCB8B"},
+		Warning:   lipgloss.AdaptiveColor{Light: "#D08770", Dark: "#EBCB8B"},
+		Error:     lipgloss.AdaptiveColor{Light: "#BF616A", Dark: "#BF616A"},
+		Muted:     lipgloss.AdaptiveColor{Light: "#4C566A", Dark: "#616E88"},
+		Stripe:    lipgloss.AdaptiveColor{Light: "#E5E9F0", Dark: "#3B4252"},
+	}.styles()
+}
+
+// palette holds the colors the color theme presets are built from.
+type palette struct {
+	Accent    lipgloss.AdaptiveColor
+	Selection lipgloss.AdaptiveColor
+	Match     lipgloss.AdaptiveColor
+	Warning   lipgloss.AdaptiveColor
+	Error     lipgloss.AdaptiveColor
+	Muted     lipgloss.AdaptiveColor
+	Stripe    lipgloss.AdaptiveColor
+}
+
+// styles returns the Styles in the colors of the palette.
+func (p palette) styles() Styles {
+	s := DefaultStyles()
+	s.AltLine = defaultStyle.Background(p.Stripe)
+	s.Selected = defaultStyle.Background(p.Selection)
+	s.Marked = defaultStyle.Foreground(p.Accent).Bold(true)
+	s.SelectedMarked = s.Selected.Copy().Inherit(s.Marked)
+	s.MatchHighlight = defaultStyle.Foreground(p.Match).Underline(true)
+	s.Activatable = defaultStyle.Foreground(p.Accent)
+	s.Warning = defaultStyle.Foreground(p.Warning)
+	s.Error = defaultStyle.Foreground(p.Error)
+	s.Changed = defaultStyle.Foreground(p.Warning)
+	s.Removed = defaultStyle.Foreground(p.Error).Strikethrough(true)
+	s.FocusedAction = defaultStyle.Foreground(p.Accent).Reverse(true)
+	s.Header = defaultStyle.Foreground(p.Accent).Bold(true)
+	s.Title = defaultStyle.Foreground(p.Accent).Bold(true)
+	s.ScrollbarThumb = defaultStyle.Foreground(p.Accent)
+	s.Loading = defaultStyle.Foreground(p.Accent)
+	s.CurrentLineNumber = defaultStyle.Foreground(p.Accent).Bold(true)
+	for _, muted := range []*lipgloss.Style{&s.FilterBar, &s.Placeholder, &s.EmptyTree, &s.StatusLine, &s.LineNumber, &s.ChildCount, &s.Description, &s.Suffix, &s.ScrollbarTrack} {
+		*muted = defaultStyle.Foreground(p.Muted)
+	}
+	s.Symbol = Style(defaultStyle.Foreground(p.Muted))
+	return s
+}
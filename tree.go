@@ -1,58 +1,298 @@
 package tree
 
 import (
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/muesli/reflow/truncate"
 	"golang.org/x/exp/constraints"
 )
 
 // Model is the Bubble Tea model for this user interface.
 type Model struct {
-	root  Node
-	nodes Nodes // all nodes
-
-	view viewport.Model
+	tree  Nodes // top level nodes, as given to New, there can be any number of them
+	nodes Nodes // all nodes, flattened once per change of the tree, see refresh
+
+	version   int // bumped by the changes of the tree, see refresh
+	flattened int // the version the nodes were flattened at
+
+	view   viewport.Model
+	lines  map[Node]string // rendered nodes in and near the viewport, see materialize
+	tall   []tall          // the nodes taking up more than one row, by their index
+	frame  *frame          // the last rendered view, shared between copies of the model
+	height int             // total height, the viewport gets what is left after the other sections
+
+	pinned     Nodes
+	pinCursor  int  // the selected row of the pinned section
+	pinFocused bool // the cursor is in the pinned section instead of the tree
+
+	less func(a, b Node) bool // orders the siblings, nil keeps the order of Children()
+
+	leafOnly bool // only leaves can hold the cursor
+
+	expansion ExpansionPolicy // applied to the nodes given to New
+
+	filter   func(Node) bool // nodes for which it returns false are hidden
+	filtered Nodes           // nodes hidden by the filter
+
+	hide       func(Node) bool // nodes for which it returns true are hidden, unless showHidden is set
+	hidden     Nodes           // nodes hidden by the predicate
+	showHidden bool
+
+	filterQuery   string // set only by SetFilterQuery, used for highlighting
+	filterBar     FilterBarPosition
+	filterInput   textinput.Model
+	filterSeq     int  // identifies the latest debounced query
+	filterPending bool // the typed query hasn't been applied yet
+
+	// FilterDebounce postpones filtering until the typing into the filter bar
+	// pauses for this long, useful for huge trees, zero filters on every key
+	FilterDebounce time.Duration
+
+	search       search
+	matcher      Matcher // used by search and filter, SubstringMatcher if nil
+	plainMatcher Matcher // restored when the regexp mode is toggled off
+	searchTarget SearchTarget
+	caseMode     CaseMode
+	hideStatus   bool    // the status line isn't shown while searching
+	flatResults  bool    // the matches of the search are listed instead of the tree
+	queries      queries // entered search and filter queries
+
+	placeholders map[Node]*placeholder // rendered for the expanded nodes without children
+	chunks       map[Node]*more        // rendered in place of the children beyond the shown chunks
+	chunkSize    int
+	nodeErrs     map[Node]error // the errors of the nodes, see SetNodeError
+
+	autoRefresh  autoRefresh
+	subscription subscription
+
+	rowActions []RowAction
+	rowAction  int // focused action of the row under the cursor, -1 if none
+
+	fullWidthSelection bool
+	lineNumbers        LineNumbers
+	childCount         ChildCount
+	wrap               bool
+	alignPrefixes      bool
+	prefixWidths       []int // given by the host, measured if empty
+	prefixColumns      []int // the widths of the columns of the prefixes in use
+	title              string
+	columns            []Column
+	columnWidths       []int
+	stickyLimit        int
+	titleBar           string
+	status             string // set by the host, shown in the status line
+	emptyMessage       string
+	changeHighlight    time.Duration
+	changes            map[Node]change // highlighted by UpdateNodes
+	ghosts             []*ghost        // the nodes removed by UpdateNodes
+	changeID           int
+	initialPath        string
+	errs               []error       // the problems with the nodes since the last refresh, see Err
+	broken             map[Node]bool // the nodes whose problems have been recorded in errs
+	zoom               Nodes         // the stack of the nodes zoomed into, see ZoomIn
+	events             []tea.Msg     // the changes to be emitted from Update, see setCollapsed
+	loading            bool
+	loadingMessage     string
+	spinner            spinner.Model
+	renderer           *lipgloss.Renderer
+	scrollbar          bool
+
+	striping bool
+	stripes  map[Node]bool // every other row, computed in refresh
+
+	severityRollup bool
+	severities     map[Node]Severity // rolled up in refresh
+
+	undo          []snapshot
+	redo          []snapshot
+	expansionUndo []snapshot // view only undo, separate from the one for marks and jumps
+
+	history []Node
+	arrived time.Time // when the cursor arrived at the current node, zero if it shouldn't be recorded
+
+	// HistorySize is the maximum number of nodes kept in the history
+	HistorySize int
+	// HistoryDwell is the minimum time the cursor has to rest on a node for it to be recorded in the history
+	HistoryDwell time.Duration
+
+	mode      mode
+	prompt    textinput.Model
+	promptErr error // error of the last command run from the prompt
+	editor    textinput.Model
 
 	focus  bool // could be useful, currently unused
 	cursor int
 
-	KeyMap  KeyMap
-	Styles  Styles
-	Symbols Symbols
+	KeyMap     KeyMap
+	Styles     Styles
+	Symbols    Symbols
+	Indicators Indicators
+	// Truncation determines how the names which don't fit are shortened
+	Truncation Truncation
+	// Scrollbar holds the glyphs of the scrollbar, see SetScrollbar
+	Scrollbar Scrollbar
+
+	// Alignment is used for all nodes which don't implement Aligner
+	Alignment Alignment
+
+	translator Translator
+
+	// Clipboard is used by CopyPath, OSC52Clipboard if not set
+	Clipboard ClipboardWriter
+	// CopySeparator joins the names of the nodes in the path copied by CopyPath
+	CopySeparator string
+
+	// OnSelect, if set, is called from Update with the newly selected node, see SelectionChangedMsg.
+	// The node is nil when the cursor is on a placeholder row, or the tree is empty.
+	OnSelect func(Node) tea.Cmd
+	// OnExpand, if set, is called from Update with the expanded node, see NodeExpandedMsg
+	OnExpand func(Node) tea.Cmd
+	// OnCollapse, if set, is called from Update with the collapsed node, see NodeCollapsedMsg
+	OnCollapse func(Node) tea.Cmd
 }
 
-// New initializes a new Model
-// It sets the default content, keymap, styles, and symbols.
-func New(ns Nodes) Model {
-	// TODO: maybe assert that Nodes isn't empty or something
-	root := ns[0]
-	root.SetState(root.State() | NodeSelected) // we're selecting the first row by default
+// Option configures the Model in New.
+type Option func(*Model)
+
+// The size of the tree until it's set with WithSize or a tea.WindowSizeMsg arrives.
+const (
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
+// WithSize sets the initial size of the tree, so it renders properly
+// even before the first tea.WindowSizeMsg.
+func WithSize(w, h int) Option {
+	return func(m *Model) {
+		m.view.Width = w
+		m.height = h
+	}
+}
 
+// New initializes a new Model
+// It sets the default content, keymap, styles, and symbols, which can be
+// changed with the given options.
+// The nodes are the top level ones, there can be any number of them, none included,
+// and they're connected to each other just like the siblings are.
+func New(ns Nodes, opts ...Option) Model {
 	m := Model{
-		root:  root,
-		nodes: ns.flatten(),
+		// the nodes are reordered in place, see MoveNodeUp
+		tree: slices.Clone(ns),
+
+		view:    viewport.New(DefaultWidth, DefaultHeight),
+		height:  DefaultHeight,
+		frame:   &frame{},
+		prompt:  newPrompt(),
+		spinner: spinner.New(),
+		editor:  textinput.New(),
+
+		filterInput: textinput.New(),
+
+		placeholders: map[Node]*placeholder{},
+		chunks:       map[Node]*more{},
+		nodeErrs:     map[Node]error{},
+
+		rowAction: -1,
+
+		searchTarget: SearchName,
+
+		arrived: time.Now(),
 
-		view: viewport.New(0, 0),
+		changeHighlight: DefaultChangeHighlight,
+
+		HistorySize:  DefaultHistorySize,
+		HistoryDwell: DefaultHistoryDwell,
+
+		CopySeparator: PathSeparator,
 
 		KeyMap:  DefaultKeyMap(),
 		Styles:  DefaultStyles(),
 		Symbols: DefaultSymbols(),
+
+		Indicators: DefaultIndicators(),
+		Truncation: DefaultTruncation(),
+		Scrollbar:  DefaultScrollbar(),
 	}
 
+	for _, opt := range opts {
+		opt(&m)
+	}
+	m.Styles = m.Styles.WithRenderer(m.renderer)
+	if first := m.tree.visible().sorted(m.less); len(first) > 0 {
+		first[0].SetState(first[0].State() | NodeSelected) // we're selecting the first row by default
+	}
+	m.tree.expand(m.expansion)
+	m.layout()
+
 	// rendering all nodes, every single one of them expanded as the inital state
-	initialContent := m.renderAllNodes()
-	m.view.SetContent(
-		lipgloss.JoinVertical(lipgloss.Left, initialContent...),
-	)
+	m.refresh()
+	if m.initialPath != "" {
+		if n := m.FindPath(m.initialPath); m.revealable(n) {
+			m.reveal(n)
+		}
+	}
 
 	return m
 }
 
+// refresh re-flattens the tree and re-renders all of the visible nodes.
+func (m *Model) refresh() {
+	m.version++
+	m.rerender()
+}
+
+// rerender re-renders the nodes in and near the viewport, re-flattening and re-measuring
+// the tree only if it has changed since it was flattened the last time, see refresh.
+// It's enough for the changes which don't affect which nodes are shown, e.g. of the width
+// or the styles. The wrapped nodes are the exception, they're laid out all over again.
+func (m *Model) rerender() {
+	switch {
+	case m.flattened != m.version:
+		m.errs, m.broken = nil, nil
+		m.nodes = m.flattenNodes()
+		m.flattened = m.version
+		m.measurePrefixes()
+		m.measureColumns()
+		m.rollUpSeverities()
+		m.stripeRows()
+		m.measureRows()
+	case m.wrap:
+		m.measureRows()
+	default:
+		m.lines = map[Node]string{}
+	}
+	m.setContent()
+}
+
+// rerenderNode re-renders the row of the node at the given index,
+// or drops it if it's far from the viewport, see materialize.
+func (m *Model) rerenderNode(i int) {
+	if i < 0 || i >= len(m.nodes) {
+		return
+	}
+	n := m.nodes[i]
+	delete(m.lines, n)
+	if from, to := m.window(); i < from || i >= to {
+		if h := m.measureRow(i); h != m.heightOf(i) {
+			m.setHeight(i, h)
+		}
+		return
+	}
+	m.lines[n] = m.safeRender(n)
+	if h := lipgloss.Height(m.lines[n]); h != m.heightOf(i) {
+		// shifts the rows of the nodes below it
+		m.setHeight(i, h)
+		m.setContent()
+	}
+}
+
 // just to wrap my head around it easier
 var noop tea.Cmd = nil
 
@@ -60,12 +300,63 @@ func (m Model) Init() tea.Cmd {
 	return noop
 }
 
+// Update handles the messages of the tree. Along with the commands of the
+// actions, it returns the ones emitting SelectionChangedMsg, NodeExpandedMsg
+// and NodeCollapsedMsg for the changes made while handling the message.
 func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	previous := m.SelectedNode()
+	m.events = nil
+	m, cmd := m.update(msg)
+	m.materialize()
+	if events := m.emitEvents(previous); events != nil {
+		return m, tea.Batch(cmd, events)
+	}
+	return m, cmd
+}
+
+func (m Model) update(msg tea.Msg) (Model, tea.Cmd) {
+	// metadata and fresh data are applied regardless of the focus
+	switch msg := msg.(type) {
+	case HydratedMsg:
+		m.applyHydration(msg)
+		return m, noop
+	case ChildrenLoadedMsg:
+		m.applyLoadedChildren(msg)
+		return m, m.hydrateVisible()
+	case autoRefreshMsg:
+		return m, m.updateAutoRefresh(msg)
+	case RefreshedMsg:
+		return m, m.applyRefreshed(msg)
+	case patchMsg:
+		return m, m.applyPatch(msg)
+	case spinner.TickMsg:
+		return m, m.updateSpinner(msg)
+	case changesSettledMsg:
+		m.settleChanges(msg)
+		return m, noop
+	case AddNodesMsg, RemoveNodeMsg, SetStateMsg:
+		m.applyMutation(msg)
+		return m, m.hydrateVisible()
+	}
+
 	if !m.focus {
 		// TODO: never actually rendered, but might be useful one day
 		return m, noop
 	}
 
+	if _, ok := msg.(tea.WindowSizeMsg); !ok {
+		switch m.mode {
+		case modeCommand:
+			return m.updateCommand(msg)
+		case modeSearch:
+			return m.updateSearch(msg)
+		case modeFilter:
+			return m.updateFilterBar(msg)
+		case modeRename:
+			return m.updateRename(msg)
+		}
+	}
+
 	var cmd tea.Cmd = nil
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
@@ -73,54 +364,189 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		m.SetHeight(msg.Height)
 		// TODO: what if the screen shrinks and the currently selected node
 		// isn't visible anymore?
-		return m, nil
+		return m, m.hydrateVisible()
 	case tea.KeyMsg:
-		// so we can toggle it if need be
-		previouslySelectedNode := m.cursor
-
+		if m.pinFocused {
+			if cmd, ok := m.updatePinned(msg); ok {
+				return m, cmd
+			}
+		}
+		// the actions re-render only the rows they change, if any,
+		// so the keys which don't change anything cost nothing
 		switch {
+		case m.rowAction >= 0 && key.Matches(msg, m.KeyMap.Expand):
+			return m, m.activateAction()
+		case key.Matches(msg, m.KeyMap.NextAction):
+			m.cycleAction(1)
+			return m, noop
+		case key.Matches(msg, m.KeyMap.PrevAction):
+			m.cycleAction(-1)
+			return m, noop
+		case key.Matches(msg, m.KeyMap.Expand) && m.ShowMore():
+			return m, m.hydrateVisible()
+		case m.showingResults() && key.Matches(msg, m.KeyMap.Expand):
+			return m, m.selectResult()
 		case key.Matches(msg, m.KeyMap.Expand):
-			// this requires rerendering all of the nodes
+			if _, ok := activatable(m.currentNode()); ok {
+				return m, m.Activate()
+			}
+			// only the rows of the node and its subtree change
 			m.ToggleExpand()
-			renderedRows := m.renderAllNodes()
-			m.view.SetContent(
-				lipgloss.JoinVertical(lipgloss.Left, renderedRows...),
-			)
-			return m, noop
+			m.spliceRows(m.cursor)
+			return m, m.hydrateVisible()
+		case key.Matches(msg, m.KeyMap.Command):
+			return m, m.OpenCommandPrompt()
+		case m.filterBar != FilterBarNone && key.Matches(msg, m.KeyMap.Filter):
+			return m, m.OpenFilterBar()
+		case key.Matches(msg, m.KeyMap.Search):
+			return m, m.OpenSearch()
+		case key.Matches(msg, m.KeyMap.NextMatch):
+			return m, m.NextMatch()
+		case key.Matches(msg, m.KeyMap.PrevMatch):
+			return m, m.PrevMatch()
+		case key.Matches(msg, m.KeyMap.ToggleHidden):
+			m.ToggleHidden()
+		case key.Matches(msg, m.KeyMap.ToggleLineNumbers):
+			m.cycleLineNumbers()
+			return m, m.hydrateVisible()
+		case key.Matches(msg, m.KeyMap.ToggleMark):
+			m.ToggleMark()
+		case key.Matches(msg, m.KeyMap.TogglePin):
+			m.TogglePin()
+		case key.Matches(msg, m.KeyMap.HistoryBack):
+			cmd = m.HistoryBack()
+		case key.Matches(msg, m.KeyMap.ZoomIn):
+			m.ZoomIn()
+		case key.Matches(msg, m.KeyMap.ZoomOut):
+			m.ZoomOut()
+		case key.Matches(msg, m.KeyMap.Rename):
+			return m, m.StartRename()
+		case key.Matches(msg, m.KeyMap.ContextMenu):
+			return m, m.OpenContextMenu()
+		case key.Matches(msg, m.KeyMap.MoveNodeUp):
+			return m, m.MoveNodeUp()
+		case key.Matches(msg, m.KeyMap.MoveNodeDown):
+			return m, m.MoveNodeDown()
+		case key.Matches(msg, m.KeyMap.CopyPath):
+			return m, m.CopyPath()
+		case key.Matches(msg, m.KeyMap.Undo):
+			return m, m.Undo()
+		case key.Matches(msg, m.KeyMap.Redo):
+			return m, m.Redo()
+		case key.Matches(msg, m.KeyMap.UndoExpansion):
+			return m, m.UndoExpansion()
+		case key.Matches(msg, m.KeyMap.LineUp) && m.cursor == 0:
+			m.focusPinned()
 		case key.Matches(msg, m.KeyMap.LineUp):
 			cmd = m.MoveUp(1)
 		case key.Matches(msg, m.KeyMap.LineDown):
 			cmd = m.MoveDown(1)
 		case key.Matches(msg, m.KeyMap.PageUp):
-			cmd = m.MoveUp(m.view.Height)
+			cmd = m.PageUp()
 		case key.Matches(msg, m.KeyMap.PageDown):
-			cmd = m.MoveDown(m.view.Height)
+			cmd = m.PageDown()
 		case key.Matches(msg, m.KeyMap.HalfPageUp):
-			cmd = m.MoveUp(m.view.Height / 2)
+			cmd = m.HalfPageUp()
 		case key.Matches(msg, m.KeyMap.HalfPageDown):
-			cmd = m.MoveDown(m.view.Height / 2)
+			cmd = m.HalfPageDown()
 		case key.Matches(msg, m.KeyMap.GotoTop):
 			cmd = m.GotoTop()
 		case key.Matches(msg, m.KeyMap.GotoBottom):
 			cmd = m.GotoBottom()
 		}
-
-		newlySelectedNode := m.cursor
-		// TODO: this requires a viewport fork
-		m.view.ReplaceLine(previouslySelectedNode, m.renderNode(m.nodes.at(previouslySelectedNode)))
-		m.view.ReplaceLine(newlySelectedNode, m.renderNode(m.nodes.at(newlySelectedNode)))
 	}
 
-	return m, cmd
+	return m, tea.Batch(cmd, m.hydrateVisible())
 }
 
 func (m Model) View() string {
-	return m.view.View()
+	// scrolled outside of Update, rendering them into a copy keeps the cache as it is
+	m = m.materializedCopy()
+	v := m.compose()
+	m.frame.lines = strings.Split(v, "\n")
+	return v
+}
+
+// compose joins all of the sections of the tree into its view.
+func (m Model) compose() string {
+	sections := []string{}
+	if m.titleShown() {
+		sections = append(sections, m.titleView())
+	}
+	if m.filterBarShown() && m.filterBar == FilterBarTop {
+		sections = append(sections, m.filterBarView())
+	}
+	if rows := m.pinnedRows(); rows > 0 {
+		sections = append(sections, m.pinnedView(rows))
+	}
+	if m.headerShown() {
+		sections = append(sections, m.headerView())
+	}
+	sections = append(sections, m.treeView())
+	if m.statusShown() {
+		sections = append(sections, m.statusView())
+	}
+	if m.filterBarShown() && m.filterBar == FilterBarBottom {
+		sections = append(sections, m.filterBarView())
+	}
+	if m.prompting() {
+		sections = append(sections, m.promptView())
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, sections...)
+}
+
+// treeView renders the viewport, along with the gutter and the scrollbar if they're shown.
+func (m Model) treeView() string {
+	if m.loading {
+		return m.loadingView()
+	}
+	if m.empty() {
+		return m.emptyView()
+	}
+	if m.lineNumbers == LineNumbersNone && !m.scrollbar {
+		return m.withSticky(m.viewportView(m.view.Width))
+	}
+	parts := []string{}
+	if m.lineNumbers != LineNumbersNone {
+		parts = append(parts, m.gutterView())
+	}
+	parts = append(parts, m.withSticky(m.viewportView(m.contentWidth())))
+	if m.scrollbar {
+		parts = append(parts, m.scrollbarView())
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, parts...)
+}
+
+// contentWidth returns the width left for the rows of the tree after the gutter and the scrollbar.
+func (m Model) contentWidth() int {
+	return max(m.Width()-m.gutterWidth()-m.scrollbarWidth(), 0)
+}
+
+// layout gives the viewport whatever height is left after the other sections
+// (title, pinned nodes, header, command prompt, filter bar, status line) have been accounted for.
+func (m *Model) layout() {
+	reserved := m.pinnedRows()
+	if m.prompting() {
+		reserved++
+	}
+	if m.filterBarShown() {
+		reserved++
+	}
+	if m.statusShown() {
+		reserved++
+	}
+	if m.headerShown() {
+		reserved++
+	}
+	if m.titleShown() {
+		reserved++
+	}
+	m.view.Height = max(m.height-reserved, 0)
 }
 
 func (m *Model) setCursor(newCursorPos int) tea.Cmd {
 	// nothing changes if nothing changes
-	if cursorNotMoved := newCursorPos == m.cursor; cursorNotMoved {
+	if cursorNotMoved := newCursorPos == m.cursor; cursorNotMoved || len(m.nodes) == 0 {
 		return noop
 	}
 
@@ -130,28 +556,132 @@ func (m *Model) setCursor(newCursorPos int) tea.Cmd {
 	// that ^NodeSelected overflows
 	previous.SetState(previous.State() ^ NodeSelected)
 
+	m.recordVisit(previous)
+
 	// move cursor
+	previousCursorPos := m.cursor
 	m.cursor = newCursorPos
+	m.rowAction = -1
 
 	// select the new one
 	current := m.currentNode()
 	current.SetState(current.State() | NodeSelected)
 
+	m.rerenderNode(previousCursorPos)
+	m.rerenderNode(m.cursor)
+
 	return noop
 }
 
-// currentNode returns the currently selected node.
+// currentNode returns the currently selected node, nil if there are no visible nodes.
 func (m Model) currentNode() Node {
-	return m.nodes.at(m.cursor)
+	if m.cursor < 0 || m.cursor >= len(m.nodes) {
+		return nil
+	}
+	return m.nodes[m.cursor]
+}
+
+// refreshAndSelect re-flattens and re-renders the tree, moving the cursor
+// to the target node. If the target isn't visible (or nil) the cursor stays
+// on the same row, or the last one if the tree got shorter.
+func (m *Model) refreshAndSelect(target Node) {
+	if len(m.nodes) > 0 {
+		previous := m.currentNode()
+		previous.SetState(previous.State() &^ NodeSelected)
+	}
+	if target != nil {
+		target.SetState(target.State() | NodeSelected)
+	}
+
+	m.refresh()
+	if len(m.nodes) == 0 {
+		m.cursor = 0
+		return
+	}
+
+	if i := m.nodes.index(target); i != -1 {
+		m.cursor = i
+	} else {
+		if target != nil {
+			target.SetState(target.State() &^ NodeSelected)
+		}
+		m.cursor = clamp(m.cursor, 0, len(m.nodes)-1)
+		current := m.currentNode()
+		current.SetState(current.State() | NodeSelected)
+		// rendered before the selection was known
+		m.rerender()
+	}
+	m.scrollToCursor()
 }
 
+// scrollToCursor moves the viewport just enough for the cursor to be visible.
+// The first row of a node taller than the viewport is preferred.
+func (m *Model) scrollToCursor() {
+	top, bottom := m.view.YOffset, m.view.YOffset+m.view.Height-1
+	first := m.rowOf(m.cursor)
+	last := first + m.heightOf(m.cursor) - 1
+	switch {
+	case first < top:
+		m.setYOffset(first)
+	case last > bottom:
+		m.setYOffset(min(last-m.view.Height+1, first))
+	}
+	for m.view.YOffset > 0 && m.coveredBySticky(m.cursor) {
+		m.setYOffset(m.view.YOffset - 1)
+	}
+}
+
+// AllNodes returns the rows of the tree in the order they're rendered in, the placeholders
+// of the expanded nodes without children included, so they match Cursor() and Lines().
+// See VisibleNodes for the nodes only.
 func (m Model) AllNodes() Nodes {
 	return m.nodes
 }
 
+// VisibleNodes returns the nodes which are currently rendered, in order, i.e. the ones
+// which aren't hidden and whose ancestors are all expanded, or the results of the search,
+// see SetFlatResults. Unlike AllNodes, it never contains the placeholders.
+func (m Model) VisibleNodes() Nodes {
+	res := make(Nodes, 0, len(m.nodes))
+	for _, n := range m.nodes {
+		if !isPlaceholder(n) {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// SelectedNode returns the node under the cursor, or nil if the tree is empty
+// or the cursor is on a placeholder.
+func (m Model) SelectedNode() Node {
+	n := m.currentNode()
+	if n == nil || isPlaceholder(n) {
+		return nil
+	}
+	return n
+}
+
+// CurrentPath returns the nodes from the top level one down to the selected one,
+// e.g. for rendering breadcrumbs, or nil if there's no selected node, see SelectedNode.
+func (m Model) CurrentPath() Nodes {
+	n := m.SelectedNode()
+	if n == nil {
+		return nil
+	}
+	ancestors := Ancestors(n)
+	res := make(Nodes, 0, len(ancestors)+1)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		res = append(res, ancestors[i])
+	}
+	return append(res, n)
+}
+
 // MoveUp moves the selection up by any number of rows.
 // It can not go above the first row.
 func (m *Model) MoveUp(n int) tea.Cmd {
+	if m.leafOnly {
+		return m.moveLeaves(n, -1)
+	}
 	if cursorAtTop := m.cursor == 0; cursorAtTop {
 		return noop
 	}
@@ -159,65 +689,175 @@ func (m *Model) MoveUp(n int) tea.Cmd {
 	minCursorPos := 0
 	newCursorPos := max(m.cursor-n, minCursorPos)
 
-	viewTop, _ := m.view.VisibleLineIndices()
-	if cursorBrokeLimit := newCursorPos < viewTop; cursorBrokeLimit {
-		// gotta move the view to follow the cursor
-		m.view.LineUp(newCursorPos - m.cursor)
-	}
-	return m.setCursor(newCursorPos)
+	// gotta move the view to follow the cursor
+	return m.jumpTo(newCursorPos)
 }
 
 // MoveDown moves the selection down by any number of rows.
 // It can not go below the last row.
 func (m *Model) MoveDown(n int) tea.Cmd {
-	maxCursorPos := m.view.TotalLineCount() - 1
-	if cursorAtBottom := m.cursor == maxCursorPos; cursorAtBottom {
+	if m.leafOnly {
+		return m.moveLeaves(n, 1)
+	}
+	maxCursorPos := len(m.nodes) - 1
+	if cursorAtBottom := m.cursor >= maxCursorPos; cursorAtBottom {
 		return noop
 	}
 
 	newCursorPos := min(m.cursor+n, maxCursorPos)
 
-	_, viewBottom := m.view.VisibleLineIndices()
-	if cursorBrokeLimit := viewBottom < newCursorPos; cursorBrokeLimit {
-		// gotta move the view to follow the cursor
-		m.view.LineDown(newCursorPos - m.cursor)
+	// gotta move the view to follow the cursor
+	return m.jumpTo(newCursorPos)
+}
+
+// PageUp moves the selection up by one viewport height.
+func (m *Model) PageUp() tea.Cmd {
+	return m.MoveUp(m.view.Height)
+}
+
+// PageDown moves the selection down by one viewport height.
+func (m *Model) PageDown() tea.Cmd {
+	return m.MoveDown(m.view.Height)
+}
+
+// HalfPageUp moves the selection up by half of the viewport height.
+// Unlike in the viewport, it's bound only to ctrl+u by default, since u is bound to Undo.
+func (m *Model) HalfPageUp() tea.Cmd {
+	return m.MoveUp(m.view.Height / 2)
+}
+
+// HalfPageDown moves the selection down by half of the viewport height.
+func (m *Model) HalfPageDown() tea.Cmd {
+	return m.MoveDown(m.view.Height / 2)
+}
+
+// ScrollBy scrolls the viewport by n rows, down for a positive n and up for a negative one.
+// Unlike MoveDown and MoveUp, the selection stays where it is, unless it would
+// end up outside of the viewport, in which case it gets dragged along.
+func (m *Model) ScrollBy(n int) tea.Cmd {
+	if len(m.nodes) == 0 {
+		return noop
+	}
+	m.setYOffset(m.view.YOffset + n)
+	top, bottom := m.visibleRows()
+	return m.setCursor(clamp(m.cursor, m.nodeAtRow(min(top+len(m.sticky()), bottom)), m.nodeAtRow(bottom)))
+}
+
+// GotoLine moves the selection to the n-th visible row, counting from 1.
+// Numbers out of range select the first or the last row.
+func (m *Model) GotoLine(n int) tea.Cmd {
+	if len(m.nodes) == 0 {
+		return noop
 	}
-	return m.setCursor(newCursorPos)
+	row := clamp(n-1, 0, len(m.nodes)-1)
+	if row != m.cursor {
+		m.saveUndo()
+	}
+	return m.jumpTo(row)
 }
 
 // GotoTop moves the selection to the first row.
 func (m *Model) GotoTop() tea.Cmd {
-	return m.MoveUp(m.view.TotalLineCount())
+	if m.cursor != 0 {
+		m.saveUndo()
+	}
+	if m.leafOnly {
+		return m.jumpToLeaf(-1, 1)
+	}
+	return m.MoveUp(m.totalRows())
 }
 
 // GotoBottom moves the selection to the last row.
 func (m *Model) GotoBottom() tea.Cmd {
-	return m.MoveDown(m.view.TotalLineCount())
+	if m.cursor != len(m.nodes)-1 {
+		m.saveUndo()
+	}
+	if m.leafOnly {
+		return m.jumpToLeaf(len(m.nodes), -1)
+	}
+	return m.MoveDown(m.totalRows())
 }
 
 // ToggleExpand toggles the expanded state of the node pointed at by m.cursor
 func (m *Model) ToggleExpand() {
 	n := m.currentNode()
-	if !isCollapsible(n) {
+	if n == nil || !isCollapsible(n) {
 		return
 	}
-	n.SetState(n.State() ^ NodeCollapsed)
+	m.saveUndoOf(n)
+	m.saveExpansionOf(n)
+	m.setCollapsed(n, isExpanded(n))
+}
+
+// ToggleMark toggles the marked state of the node pointed at by m.cursor
+func (m *Model) ToggleMark() {
+	n := m.currentNode()
+	if n == nil || isPlaceholder(n) {
+		return
+	}
+	m.saveUndo()
+	n.SetState(n.State() ^ NodeMarked)
+	m.rerenderNode(m.cursor)
+}
+
+// ToggleMarkMatching toggles the marked state of all visible nodes matching the glob pattern
+// and returns the number of toggled nodes.
+// Patterns without a PathSeparator are matched against node names, e.g. "*.log",
+// the rest against the path relative to the top level node, e.g. "internal/**".
+func (m *Model) ToggleMarkMatching(pattern string) (int, error) {
+	if err := validateGlob(pattern); err != nil {
+		return 0, err
+	}
+
+	before := m.snapshot()
+	count := 0
+	for _, n := range m.nodes {
+		if !isPlaceholder(n) && matchGlob(pattern, n) {
+			n.SetState(n.State() ^ NodeMarked)
+			count++
+		}
+	}
+	if count > 0 {
+		m.pushUndo(before)
+		m.rerender()
+	}
+	return count, nil
+}
+
+// Marked returns all of the marked nodes, including the ones not currently visible.
+func (m Model) Marked() Nodes {
+	marked := Nodes{}
+	for _, n := range m.tree.all() {
+		if isMarked(n) {
+			marked = append(marked, n)
+		}
+	}
+	return marked
 }
 
 // SetWidth sets the width of the viewport of the tree.
+// The rows are re-rendered to fit the new width.
 func (m *Model) SetWidth(w int) {
+	if w == m.view.Width {
+		return
+	}
 	m.view.Width = w
+	m.rerender()
 }
 
-// SetHeight sets the height of the viewport of the tree.
+// SetHeight sets the height of the tree, including the pinned section and the prompt.
 func (m *Model) SetHeight(h int) {
-	// TODO: make sure the currently selected node is still visible
-	m.view.Height = h
+	m.height = h
+	m.layout()
+	if len(m.nodes) > 0 {
+		// the cursor stays visible, e.g. the node selected by WithInitialPath on the first tea.WindowSizeMsg
+		m.scrollToCursor()
+	}
 }
 
-// Height returns the viewport height of the tree.
+// Height returns the height of the tree, including the pinned section and the prompt.
 func (m Model) Height() int {
-	return m.view.Height
+	return m.height
 }
 
 // Width returns the viewport width of the tree.
@@ -232,12 +872,16 @@ func (m Model) YOffset() int {
 
 // SetYOffset sets Y offset of the tree's viewport.
 func (m *Model) SetYOffset(n int) {
-	m.view.SetYOffset(n)
+	m.setYOffset(n)
 }
 
 // ScrollPercent returns the amount scrolled as a float between 0 and 1.
 func (m Model) ScrollPercent() float64 {
-	return m.view.ScrollPercent()
+	total := m.totalRows()
+	if m.view.Height >= total {
+		return 1
+	}
+	return clamp(float64(m.view.YOffset)/float64(total-1-m.view.Height), 0, 1)
 }
 
 // Cursor returns the index of the selected row.
@@ -269,38 +913,38 @@ func (m *Model) Focus() {
 
 // Blur blurs the tree, preventing selection or movement.
 func (m *Model) Blur() {
-	current := m.currentNode()
-	current.SetState(current.State() ^ NodeSelected)
+	if current := m.currentNode(); current != nil {
+		current.SetState(current.State() ^ NodeSelected)
+	}
 	m.focus = false
 }
 
 // When we render the tree symbols we consider them as a grid of maxDepth width
-// Each pos in the grid corresponds to a space or a tree-depth-indicating symbol
+// Each pos in the grid corresponds to a space or a tree-depth-indicating symbol.
+// The lineage holds the node followed by its ancestors, see lineage, and the depth
+// is reset to zero at zoomDepth for the zoomed in subtree.
 // TODO: good luck
-func (m Model) getTreeSymbolForPos(n Node, pos int, maxDepth int) string {
-	if n == nil {
-		// TODO: find out how can this happen? ( Luka M. 2024-01-21 )
-		panic("getting tree symbol for nil node")
-	}
+func (m Model) getTreeSymbolForPos(lineage Nodes, pos, maxDepth, zoomDepth int) string {
+	depth := pos - zoomDepth
 	s := m.Styles.Symbol
-	if hasPaddingAtPos(n, pos, maxDepth) {
-		return Padding(s, m.Symbols, pos)
+	if m.Styles.SymbolByDepth != nil {
+		s = Style(m.Styles.SymbolByDepth(depth))
+	}
+	if hasPaddingAtPos(lineage, pos, maxDepth) {
+		return Padding(s, m.Symbols, depth)
 	}
 	if pos < maxDepth {
-		return RenderConnector(s, m.Symbols, pos)
+		return RenderConnector(s, m.Symbols, depth)
 	}
-	if isLastNode(n) {
-		return RenderTerminator(s, m.Symbols, pos)
+	if isLastNode(lineage[0]) {
+		return RenderTerminator(s, m.Symbols, depth)
 	}
-	return RenderStarter(s, m.Symbols, pos)
+	return RenderStarter(s, m.Symbols, depth)
 }
 
 // hasPaddingAtPos computes if a node of given given depth needs padding in the tree-like view
 // TODO: good luck
-func hasPaddingAtPos(n Node, depth int, maxDepth int) bool {
-	if n == nil {
-		return true
-	}
+func hasPaddingAtPos(lineage Nodes, depth int, maxDepth int) bool {
 	if depth > maxDepth {
 		return true
 	}
@@ -308,138 +952,222 @@ func hasPaddingAtPos(n Node, depth int, maxDepth int) bool {
 		return false
 	}
 	parentInPos := maxDepth - depth
-	for i := 0; i < parentInPos; i++ {
-		if n = n.Parent(); n == nil {
-			return true
-		}
+	if parentInPos >= len(lineage) {
+		return true
 	}
-	return isLastNode(n)
+	return isLastNode(lineage[parentInPos])
+}
+
+// lineage returns the node followed by all of its ancestors, up to the top level one,
+// so that the ancestor at any depth is looked up without walking up the tree again.
+func lineage(n Node) Nodes {
+	res := Nodes{}
+	for ; n != nil; n = n.Parent() {
+		res = append(res, n)
+	}
+	return res
 }
 
 // TODO: good luck
 func (m Model) renderSymbolsForSingleLineNode(n Node) string {
-	nodeDepth := getDepth(n)
+	lineage := lineage(n)
+	nodeDepth, zoomDepth := len(lineage)-1, m.zoomDepth()
+	if w := (nodeDepth - zoomDepth + 1) * width(m.Symbols); m.Width() > 0 && w > m.rowWidth() {
+		// too deep to fit, fitPrefix drops them anyway, no point in drawing them
+		return strings.Repeat(" ", w)
+	}
 
 	prefix := strings.Builder{}
-	for pos := 0; pos <= nodeDepth; pos++ {
-		prefix.WriteString(m.getTreeSymbolForPos(n, pos, nodeDepth))
+	for pos := zoomDepth; pos <= nodeDepth; pos++ {
+		prefix.WriteString(m.getTreeSymbolForPos(lineage, pos, nodeDepth, zoomDepth))
 	}
 	return prefix.String()
 }
 
-// TODO: good luck
-func (m Model) renderPrefixForMultiLineNode(t Node, lineCount int) string {
-	maxDepth := getDepth(t)
-
-	s := m.Styles.Symbol
-
+// renderSymbolsForContinuation renders the tree symbols of the rows following the first one of a node,
+// continuing the connectors of the node and its ancestors down to their next siblings.
+func (m Model) renderSymbolsForContinuation(n Node) string {
+	lineage := lineage(n)
+	depth, zoomDepth := len(lineage)-1, m.zoomDepth()
 	prefix := strings.Builder{}
-
-	connectsBottom := isLastNode(t)
-	for line := 0; line < lineCount; line++ {
-		for lvl := 0; lvl <= maxDepth-1; lvl++ {
-			prefix.WriteString(m.getTreeSymbolForPos(t, lvl, maxDepth))
-		}
-		if line == 0 {
-			prefix.WriteString(RenderStarter(s, m.Symbols, maxDepth))
-			if lineCount > 1 {
-				prefix.WriteRune('\n')
-			}
-		} else if line == lineCount-1 {
-			if !connectsBottom {
-				prefix.WriteString(RenderTerminator(s, m.Symbols, maxDepth))
-			} else {
-				prefix.WriteString(RenderConnector(s, m.Symbols, maxDepth))
-			}
-		} else {
-			prefix.WriteString(RenderConnector(s, m.Symbols, maxDepth))
-			prefix.WriteRune('\n')
-		}
+	for pos := zoomDepth; pos < depth; pos++ {
+		prefix.WriteString(m.getTreeSymbolForPos(lineage, pos, depth, zoomDepth))
+	}
+	s := m.Styles.Symbol
+	if m.Styles.SymbolByDepth != nil {
+		s = Style(m.Styles.SymbolByDepth(depth - zoomDepth))
+	}
+	if isLastNode(n) {
+		prefix.WriteString(Padding(s, m.Symbols, depth-zoomDepth))
+	} else {
+		prefix.WriteString(RenderConnector(s, m.Symbols, depth-zoomDepth))
 	}
-
 	return prefix.String()
 }
 
-// TODO: good luck
-func (m *Model) render() []string {
-	if m.view.Height+m.view.Width == 0 {
-		return nil
+// renderPrefixForContinuation renders everything left of the content of the rows
+// following the first one of a node, e.g. the rest of a multi-line name or the description,
+// so that the content is aligned with the name following the prefix and the decorations
+// of the given widths.
+func (m Model) renderPrefixForContinuation(n Node, prefixWidth, decorationsWidth int) string {
+	symbols := ""
+	if prefixWidth > 0 {
+		// the tree symbols are always at the end of the prefix, whatever was left of them
+		symbols = m.renderSymbolsForContinuation(n)
 	}
-
-	return m.renderNodes(m.AllNodes())
+	lead := max(prefixWidth-textWidth(symbols), 0)
+	return strings.Repeat(" ", lead) + symbols + strings.Repeat(" ", decorationsWidth)
 }
 
+// Ellipsis is the default Truncation.Ellipsis.
 const Ellipsis = "…"
 
 // SetStyles sets the tree Styles.
 func (m *Model) SetStyles(s Styles) {
-	m.Styles = s
+	m.Styles = s.WithRenderer(m.renderer)
 }
 
 // TODO: good luck
 func (m *Model) renderNode(n Node) string {
-	if n == nil {
-		// TODO: find out how can this happen? ( Luka M. 2024-01-21 )
-		panic("trying to render nil node")
-		// return ""
+	if m.showingResults() {
+		return m.renderResult(n)
 	}
 
 	// TODO: multiline content issue will be solved when viewport gets horizontal scrolling (https://github.com/charmbracelet/bubbles/issues/145)
-	// the prefix consists of custom Prefix function + tree-like symbols (depth, branching)
-	prefix := n.Prefix() + m.renderSymbolsForSingleLineNode(n)
+	prefix := m.renderPrefix(n)
+	decorations := m.renderIcon(n)
+	switch m.Indicators.Placement {
+	case IndicatorBeforeName:
+		decorations = m.renderIndicator(n) + decorations
+	case IndicatorBeforePrefix:
+		prefix = m.renderIndicator(n) + prefix
+	}
+	if m.mode == modeRename && isSelected(n) {
+		return lipgloss.JoinHorizontal(lipgloss.Left, prefix, decorations, m.editor.View())
+	}
+
+	// every line of a multi-line name gets a row of its own
+	name, badge, suffix := n.Name(), m.renderBadge(n), m.renderSuffix(n)
+	lines := strings.Split(name, "\n")
+	// nothing to fit into if the size is not known yet
+	nameWidth, descWidth := textWidth(name), 0
+	if m.Width() > 0 {
+		prefix, nameWidth = m.fitPrefix(n, prefix)
+		decorations, nameWidth = fitDecorations(decorations, nameWidth)
+		suffix, nameWidth = fitDecorations(suffix, nameWidth)
+		badge, nameWidth = fitDecorations(badge, nameWidth)
+		descWidth = nameWidth + textWidth(badge)
+		lines = m.fitLines(lines, nameWidth)
+	}
 
-	prefixWidth := lipgloss.Width(prefix)
-	nameWidth := m.Width() - prefixWidth
-	style := m.Styles.Line
-	if isSelected(n) {
-		style = m.Styles.Selected
+	style, bar := m.nodeStyle(n), m.selectionBar(n)
+	continuation := m.renderPrefixForContinuation(n, textWidth(prefix), textWidth(decorations))
+	if bar {
+		prefix, decorations = restyle(style, prefix), restyle(style, decorations)
+		continuation = restyle(style, continuation)
 	}
-	render := style.Width(nameWidth).MaxWidth(nameWidth - 1).Render
-	name := n.Name()
-	if lipgloss.Width(name) > nameWidth {
-		name = truncate.StringWithTail(name, uint(nameWidth-1), Ellipsis)
+	rows := []string{lipgloss.JoinHorizontal(lipgloss.Left, prefix, decorations, m.renderNameWithBadge(n, lines[0], badge, nameWidth), suffix, m.columnsCells(n), m.actionsCell(n))}
+	for _, l := range lines[1:] {
+		rows = append(rows, continuation+m.renderName(n, l, nameWidth))
+	}
+	if desc := m.renderDescription(n, descWidth); desc != "" {
+		rows = append(rows, continuation+desc)
+	}
+	if bar {
+		for i := range rows {
+			rows[i] = m.barRow(style, rows[i])
+		}
 	}
-	node := lipgloss.JoinHorizontal(lipgloss.Left, prefix, render(name))
 	// TODO: I don't like this approach, renderNode should render only the given node!
 	// if isExpanded(n) && hasChildren(n) {
 	// renderedChildren := m.renderNodes(n.Children())
 	// node = lipgloss.JoinVertical(lipgloss.Top, node, lipgloss.JoinVertical(lipgloss.Left, renderedChildren...))
 	// }
 
-	return node
+	return strings.Join(rows, "\n")
 }
 
-// renderAllNodes returns a string representation for each node
-// both the prefix, tree-like symbols and name, omitting hidden nodes
-// TODO: good luck
-func (m Model) renderAllNodes() []string {
-	return m.renderNodes(m.AllNodes())
+// minNameWidth is the narrowest the name gets before the prefix starts being dropped.
+const minNameWidth = 3
+
+// rowWidth returns the width left for the prefix and the name.
+func (m Model) rowWidth() int {
+	return m.contentWidth() - m.columnsWidth() - m.actionsWidth()
 }
 
-// TODO: good luck
-func (m Model) renderNodes(ns Nodes) []string {
-	rendered := []string{}
-	for i, n := range ns {
-		if isHidden(n) {
-			continue
-		}
+// fitPrefix drops the parts of the prefix which don't leave enough room for
+// the name, first the custom Prefix and then the tree-like symbols.
+// It returns the prefix which fits and the width left for the name.
+func (m Model) fitPrefix(n Node, prefix string) (string, int) {
+	// leaving the last column empty
+	if nameWidth := m.rowWidth() - textWidth(prefix) - 1; nameWidth >= minNameWidth {
+		return prefix, nameWidth
+	}
+	symbols := m.renderSymbolsForSingleLineNode(n)
+	if nameWidth := m.rowWidth() - textWidth(symbols) - 1; nameWidth >= minNameWidth {
+		return symbols, nameWidth
+	}
+	// the name alone, clipped to whatever there is
+	return "", max(m.rowWidth(), 0)
+}
 
-		hints := NodeNone
-		if i > 0 {
-			hints |= NodeHasPreviousSibling
-		}
-		if hasChildren(n) {
-			hints |= NodeCollapsible
-		}
-		if i == len(ns)-1 {
-			hints |= NodeLastChild
-		}
+// renderPrefix renders everything left of the node's name, which is
+// the custom Prefix function + tree-like symbols (depth, branching)
+func (m Model) renderPrefix(n Node) string {
+	return m.alignPrefix(n.Prefix()) + m.renderSymbolsForSingleLineNode(n)
+}
 
-		n.SetState(n.State() | hints)
-		if out := m.renderNode(n); len(out) > 0 {
-			rendered = append(rendered, out)
-		}
+// alignment returns the alignment of the node's columns, preferring the node's own one.
+func (m Model) alignment(n Node) Alignment {
+	if a, ok := n.(Aligner); ok {
+		return a.Alignment()
 	}
+	return m.Alignment
+}
 
-	return rendered
+// nodeStyle returns the style of the node's name depending on whether it is
+// under the cursor, marked or both, on top of its base style.
+func (m Model) nodeStyle(n Node) lipgloss.Style {
+	style := m.baseStyle(n)
+	switch {
+	case isSelected(n) && isMarked(n):
+		return m.Styles.SelectedMarked.Copy().Inherit(style)
+	case isSelected(n):
+		return m.Styles.Selected.Copy().Inherit(style)
+	case isMarked(n):
+		return m.Styles.Marked.Copy().Inherit(style)
+	}
+	return style
+}
+
+// baseStyle returns the style of the node regardless of it being selected or marked.
+func (m Model) baseStyle(n Node) lipgloss.Style {
+	if isGhost(n) {
+		return m.Styles.Removed
+	}
+	switch m.changes[n] {
+	case changeAdded:
+		return m.Styles.Added
+	case changeChanged:
+		return m.Styles.Changed
+	}
+	if failed(n) || placeholderFailed(n) {
+		return m.Styles.Error
+	}
+	if isPlaceholder(n) {
+		return m.Styles.Placeholder
+	}
+	if style, ok := m.severityStyle(n); ok {
+		return style
+	}
+	if s, ok := n.(NodeStyler); ok {
+		return s.Style()
+	}
+	if _, ok := activatable(n); ok {
+		return m.Styles.Activatable
+	}
+	if m.stripes[n] {
+		return m.Styles.AltLine
+	}
+	return m.Styles.Line
 }
@@ -1,5 +1,22 @@
 package tree
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
 type node struct {
 	name     string
 	parent   *node
@@ -14,7 +31,11 @@ func (n node) Parent() Node {
 	return n.parent
 }
 
+// renders counts the calls of Name, which happen mostly when rendering
+var renders int
+
 func (n node) Name() string {
+	renders++
 	return n.name
 }
 
@@ -38,6 +59,17 @@ func (n node) Children() Nodes {
 	return nodes
 }
 
+func (n *node) SwapChildren(i, j int) {
+	n.children[i], n.children[j] = n.children[j], n.children[i]
+}
+
+func (n *node) SetChildren(ns Nodes) {
+	n.children = make([]*node, len(ns))
+	for i, nn := range ns {
+		n.children[i] = nn.(*node)
+	}
+}
+
 // shorthand functions for setting the parent
 func p(p *node) func(*node) {
 	return func(nn *node) {
@@ -119,4 +151,2889 @@ var treeOne = tn("tmp",
 	),
 )
 
+func TestMarkedStyles(t *testing.T) {
+	r := lipgloss.NewRenderer(io.Discard)
+	r.SetColorProfile(termenv.ANSI)
+	m := New(Nodes{tn("root", c(tn("plain"), tn("marked"), tn("both")))})
+	m.SetWidth(40)
+	m.SetHeight(5)
+	m.Styles.Line = r.NewStyle()
+	m.Styles.Selected = r.NewStyle().Foreground(lipgloss.Color("1"))
+	m.Styles.Marked = r.NewStyle().Foreground(lipgloss.Color("2"))
+	m.Styles.SelectedMarked = r.NewStyle().Foreground(lipgloss.Color("3"))
+
+	m.MoveDown(2)
+	m.ToggleMark()
+	m.MoveDown(1)
+	m.ToggleMark()
+
+	colors := map[string]string{"\x1b[31m": "selected", "\x1b[32m": "marked", "\x1b[33m": "selected and marked"}
+	check := func(want ...string) {
+		t.Helper()
+		for i, w := range want {
+			row, got := m.renderNode(m.nodes[i]), ""
+			for seq, style := range colors {
+				if strings.Contains(row, seq) {
+					got += style
+				}
+			}
+			if got != w {
+				t.Errorf("row %d should be styled as %q, got %q in %q", i, w, got, row)
+			}
+		}
+	}
+	check("", "", "marked", "selected and marked")
+	m.MoveUp(3)
+	check("selected", "", "marked", "marked")
+}
+
+// hydrating counts the calls to Hydrate, its metadata is the size appended to the name
+type hydrating struct {
+	*node
+	calls *int
+	size  *string
+}
+
+func (h hydrating) Name() string { return h.node.Name() + *h.size }
+
+func (h hydrating) Hydrate() tea.Cmd {
+	*h.calls++
+	return func() tea.Msg {
+		return HydratedMsg{Node: h, Apply: func() { *h.size = " 42B" }}
+	}
+}
+
+func TestHydrator(t *testing.T) {
+	calls := map[string]*int{}
+	nodes := Nodes{}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("n%02d", i)
+		calls[name] = new(int)
+		nodes = append(nodes, hydrating{tn(name), calls[name], new(string)})
+	}
+	m := New(nodes)
+	m.SetWidth(40)
+	m.SetHeight(5)
+	m.Focus()
+	hydrated := func() []string {
+		res := []string{}
+		for i := 0; i < 20; i++ {
+			if name := fmt.Sprintf("n%02d", i); *calls[name] > 0 {
+				res = append(res, name)
+			}
+		}
+		return res
+	}
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			for _, cmd := range msg {
+				run(cmd)
+			}
+		case HydratedMsg:
+			m, _ = m.Update(msg)
+		}
+	}
+	update := func(msg tea.Msg) {
+		var cmd tea.Cmd
+		m, cmd = m.Update(msg)
+		run(cmd)
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyDown})
+	if want := []string{"n00", "n01", "n02", "n03", "n04"}; !reflect.DeepEqual(hydrated(), want) {
+		t.Errorf("only the rows in the viewport should be hydrated, got %v", hydrated())
+	}
+	if !strings.Contains(m.View(), "n04 42B") {
+		t.Errorf("the hydrated metadata should be rendered, got\n%s", m.View())
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyPgDown})
+	update(tea.KeyMsg{Type: tea.KeyUp})
+	for name, n := range calls {
+		if *n > 1 {
+			t.Errorf("%s should be hydrated once, got %d calls", name, *n)
+		}
+	}
+	if got := hydrated(); len(got) <= 5 || got[len(got)-1] == "n19" {
+		t.Errorf("the rows scrolled into the viewport should be hydrated, got %v", got)
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyEnd})
+	if got := hydrated(); got[len(got)-1] != "n19" || !strings.Contains(m.View(), "n19 42B") {
+		t.Errorf("the last rows should be hydrated once scrolled to, got %v", got)
+	}
+}
+
+func TestPinned(t *testing.T) {
+	load := func() Nodes {
+		return Nodes{tn("root", c(tn("a", c(tn("a1"), tn("a2")), st(NodeCollapsed)), tn("b")))}
+	}
+	m := New(load(), WithSize(40, 8))
+	m.Focus()
+	keys := func(ks ...tea.KeyType) {
+		for _, k := range ks {
+			m, _ = m.Update(tea.KeyMsg{Type: k})
+		}
+	}
+	pinned := func() []string {
+		res := []string{}
+		for _, n := range m.Pinned() {
+			res = append(res, n.Name())
+		}
+		return res
+	}
+
+	m.MoveDown(2) // b
+	m.TogglePin()
+	m.TogglePin()
+	if len(m.Pinned()) != 0 {
+		t.Errorf("toggling the pin twice should unpin the node, got %v", pinned())
+	}
+	m.TogglePin()
+	a1 := m.AllNodes()[1].Children()[0]
+	m.PinNode(a1)
+	m.PinNode(a1)
+	if got := pinned(); !reflect.DeepEqual(got, []string{"b", "a1"}) {
+		t.Fatalf("the nodes should be pinned once, got %v", got)
+	}
+	if v := m.View(); !strings.Contains(v, PinIndicator+"root/a/a1") {
+		t.Errorf("the collapsed node should be pinned with its path, got\n%s", v)
+	}
+
+	m.SetNodes(load())
+	if got := m.Pinned(); len(got) != 2 || got[1] == a1 || nodePath(got[1]) != "root/a/a1" {
+		t.Errorf("the pins should be carried over to the new nodes, got %v", pinned())
+	}
+
+	m.GotoTop()
+	keys(tea.KeyUp)
+	if n, ok := m.PinnedFocused(); !ok || n.Name() != "a1" {
+		t.Fatalf("moving up from the top should move into the last pinned row, got %v", n)
+	}
+	keys(tea.KeyUp, tea.KeyUp)
+	if n, _ := m.PinnedFocused(); n.Name() != "b" || m.currentNode().Name() != "root" {
+		t.Errorf("the cursor should stay within the pinned section, got %q", n.Name())
+	}
+	keys(tea.KeyDown, tea.KeyDown)
+	if _, ok := m.PinnedFocused(); ok || m.currentNode().Name() != "root" {
+		t.Errorf("moving down from the last pinned row should move back to the tree")
+	}
+	if keys(tea.KeyPgUp); m.pinFocused {
+		t.Errorf("only moving up by a line should move into the pinned section")
+	}
+
+	keys(tea.KeyUp, tea.KeyEnter)
+	if _, ok := m.PinnedFocused(); ok || m.currentNode().Name() != "a1" {
+		t.Errorf("expanding the pinned row should jump to its node, got %q", m.currentNode().Name())
+	}
+	if !isSelected(m.currentNode()) || isSelected(m.AllNodes()[0]) {
+		t.Errorf("only the node jumped to should be selected")
+	}
+
+	m.UnpinNode(m.Pinned()[0])
+	m.UnpinNode(m.Pinned()[0])
+	m.GotoTop()
+	keys(tea.KeyUp)
+	if _, ok := m.PinnedFocused(); ok || len(m.Pinned()) != 0 || strings.Contains(m.View(), PinIndicator) {
+		t.Errorf("there should be no pinned section once all of the nodes are unpinned")
+	}
+
+	m = New(Nodes{tn("root", c(tn("empty", st(NodeCollapsible))))})
+	m.GotoBottom()
+	if m.TogglePin(); len(m.Pinned()) != 0 {
+		t.Errorf("the placeholder rows shouldn't be pinned, got %v", pinned())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	nodes := Nodes{}
+	for i := 0; i < 6; i++ {
+		nodes = append(nodes, tn(fmt.Sprintf("n%d", i)))
+	}
+	m := New(nodes)
+	m.SetWidth(40)
+	m.SetHeight(10)
+	m.HistoryDwell = 0
+	m.HistorySize = 3
+
+	for i := 0; i < 5; i++ {
+		m.MoveDown(1)
+	}
+	if want := []string{"n2", "n3", "n4"}; !reflect.DeepEqual(names(m.History()), want) {
+		t.Errorf("the history should keep only the last %d visits, got %v", m.HistorySize, names(m.History()))
+	}
+
+	m.HistoryBack()
+	if got := m.currentNode().Name(); got != "n4" || !reflect.DeepEqual(names(m.History()), []string{"n2", "n3"}) {
+		t.Errorf("going back should jump to the last visit without recording the current node, got %q and %v", got, names(m.History()))
+	}
+
+	m.RemoveNode(nodes[3])
+	if want := []string{"n2"}; !reflect.DeepEqual(names(m.History()), want) {
+		t.Errorf("the removed node should be dropped from the history, got %v", names(m.History()))
+	}
+	m.HistoryBack()
+	if got := m.currentNode().Name(); got != "n2" || len(m.History()) != 0 {
+		t.Errorf("going back should skip the removed node, got %q", got)
+	}
+	if m.HistoryBack(); m.currentNode().Name() != "n2" {
+		t.Errorf("going back with an empty history shouldn't move the cursor")
+	}
+}
+
+type aligned struct {
+	*node
+	pos lipgloss.Position
+}
+
+func (a aligned) Alignment() Alignment { return Alignment{Name: a.pos} }
+
+func TestAligner(t *testing.T) {
+	m := New(Nodes{aligned{tn("right"), lipgloss.Right}, aligned{tn("center"), lipgloss.Center}, tn("left")})
+	m.SetWidth(30)
+	m.SetHeight(5)
+	line := func(i int) string { return m.renderNode(m.nodes[i]) }
+	// the name starts right after the symbols when aligned to the left
+	start := strings.Index(line(2), "left")
+	column := func(i int) (int, int) {
+		row := line(i)
+		name := strings.TrimSpace(row[start:])
+		left := strings.Index(row[start:], name)
+		return left, len(row) - start - left - len(name)
+	}
+
+	if left, right := column(0); right != 0 || left == 0 {
+		t.Errorf("the name should be aligned to the right, got %q", line(0))
+	}
+	if left, right := column(1); left-right < -1 || left-right > 1 || left == 0 {
+		t.Errorf("the name should be centered, got %q", line(1))
+	}
+	if left, _ := column(2); left != 0 {
+		t.Errorf("the name should be aligned to the left, got %q", line(2))
+	}
+	for i := range m.nodes {
+		if w := lipgloss.Width(line(i)); w != 29 {
+			t.Errorf("row %d is %d wide, want 29", i, w)
+		}
+	}
+
+	m.Alignment = Alignment{Name: lipgloss.Right}
+	if left, right := column(2); right != 0 || left == 0 {
+		t.Errorf("the tree wide alignment should be used for the nodes without one, got %q", line(2))
+	}
+	if left, right := column(1); left-right < -1 || left-right > 1 || left == 0 {
+		t.Errorf("the alignment of the node should take precedence, got %q", line(1))
+	}
+}
+
+// collect runs the command, along with the batched and sequenced ones, returning the emitted messages
+func collect(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	if v := reflect.ValueOf(msg); v.Kind() == reflect.Slice && v.Type().Elem() == reflect.TypeOf(tea.Cmd(nil)) {
+		res := []tea.Msg{}
+		for i := 0; i < v.Len(); i++ {
+			res = append(res, collect(v.Index(i).Interface().(tea.Cmd))...)
+		}
+		return res
+	}
+	return []tea.Msg{msg}
+}
+
+func TestCopyPath(t *testing.T) {
+	copied := []string{}
+	m := New(Nodes{tn("root", c(tn("dir", c(tn("file.go")))))})
+	m.SetWidth(40)
+	m.SetHeight(5)
+	m.Focus()
+	m.Clipboard = func(text string) error {
+		copied = append(copied, text)
+		return nil
+	}
+	m.MoveDown(2)
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	msgs := collect(cmd)
+	if len(msgs) != 1 || msgs[0] != (CopiedMsg{Node: m.currentNode(), Path: "root/dir/file.go"}) {
+		t.Errorf("expected a CopiedMsg with the path, got %v", msgs)
+	}
+	if want := []string{"root/dir/file.go"}; !reflect.DeepEqual(copied, want) {
+		t.Errorf("the path should be written to the clipboard, got %v", copied)
+	}
+
+	m.CopySeparator = "::"
+	failed := errors.New("no clipboard")
+	m.Clipboard = func(text string) error {
+		copied = append(copied, text)
+		return failed
+	}
+	m.MoveUp(1)
+	if msg, ok := m.CopyPath()().(CopiedMsg); !ok || msg.Path != "root::dir" || msg.Err != failed {
+		t.Errorf("expected the error of the clipboard in the CopiedMsg, got %v", msg)
+	}
+
+	if cmd := New(Nodes{}).CopyPath(); cmd != nil {
+		t.Errorf("there should be nothing to copy in an empty tree")
+	}
+
+	var out bytes.Buffer
+	if err := OSC52ClipboardTo(&out)("root/dir"); err != nil || !strings.Contains(out.String(), "\x1b]52;c;") {
+		t.Errorf("expected the OSC52 sequence in the output, got %q, %v", out.String(), err)
+	}
+}
+
+func TestScrolling(t *testing.T) {
+	nodes := Nodes{}
+	for i := 0; i < 30; i++ {
+		nodes = append(nodes, tn(fmt.Sprintf("n%02d", i)))
+	}
+	m := New(nodes)
+	m.SetWidth(40)
+	m.SetHeight(10)
+	m.Focus()
+	inView := func() bool {
+		top, bottom := m.visibleRows()
+		return top <= m.cursor && m.cursor <= bottom
+	}
+
+	for _, tc := range []struct {
+		name         string
+		scroll       func() tea.Cmd
+		cursor, yOff int
+	}{
+		{"PageUp at the top", m.PageUp, 0, 0},
+		{"HalfPageDown", m.HalfPageDown, 5, 0},
+		{"PageDown", m.PageDown, 15, 6},
+		{"HalfPageUp", m.HalfPageUp, 10, 6},
+		{"ScrollBy down", func() tea.Cmd { return m.ScrollBy(3) }, 10, 9},
+		{"ScrollBy dragging the cursor", func() tea.Cmd { return m.ScrollBy(5) }, 14, 14},
+		{"ScrollBy past the bottom", func() tea.Cmd { return m.ScrollBy(100) }, 20, 20},
+		{"PageDown to the bottom", m.PageDown, 29, 20},
+		{"PageDown at the bottom", m.PageDown, 29, 20},
+		{"ScrollBy past the top", func() tea.Cmd { return m.ScrollBy(-100) }, 9, 0},
+		{"PageUp to the top", m.PageUp, 0, 0},
+	} {
+		tc.scroll()
+		if m.cursor != tc.cursor || m.view.YOffset != tc.yOff || !inView() {
+			t.Errorf("%s: cursor at %d with offset %d, want %d and %d", tc.name, m.cursor, m.view.YOffset, tc.cursor, tc.yOff)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	a := tn("a")
+	m := New(Nodes{tn("root", c(a, tn("b")))})
+	m.SetWidth(40)
+	m.SetHeight(5)
+	m.Focus()
+	m.MoveDown(1)
+	keys := func(ks ...tea.KeyMsg) tea.Cmd {
+		var cmd tea.Cmd
+		for _, k := range ks {
+			m, cmd = m.Update(k)
+		}
+		return cmd
+	}
+	r := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")}
+	x := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")}
+
+	keys(r, x)
+	if m.mode != modeRename || !strings.Contains(m.View(), "ax") {
+		t.Fatalf("expected the editor in place of the node, got\n%s", m.View())
+	}
+	if m.currentNode() != a {
+		t.Errorf("the keys should go to the editor instead of moving the cursor")
+	}
+	msgs := collect(keys(tea.KeyMsg{Type: tea.KeyEnter}))
+	if len(msgs) != 1 || msgs[0] != (NodeRenamedMsg{Node: a, NewName: "ax"}) {
+		t.Errorf("expected a NodeRenamedMsg, got %v", msgs)
+	}
+	if m.mode != modeNormal || a.Name() != "a" {
+		t.Errorf("confirming should stop editing and leave the node as it is, got %q", a.Name())
+	}
+
+	if msgs := collect(keys(r, x, tea.KeyMsg{Type: tea.KeyEsc})); len(msgs) != 0 || m.mode != modeNormal {
+		t.Errorf("cancelling shouldn't emit anything, got %v", msgs)
+	}
+	if v := m.View(); strings.Contains(v, "ax") || !strings.Contains(v, "a") {
+		t.Errorf("cancelling should bring back the node, got\n%s", v)
+	}
+
+	keys(r, tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeySpace})
+	if msgs := collect(keys(tea.KeyMsg{Type: tea.KeyEnter})); len(msgs) != 0 || m.mode != modeNormal {
+		t.Errorf("an empty name shouldn't be confirmed, got %v", msgs)
+	}
+
+	m = New(Nodes{tn("root", c(tn("a"), tn("empty", st(NodeCollapsible))))}, WithSize(50, 5),
+		WithLineNumbers(LineNumbersAbsolute), WithScrollbar(), WithColumns("NAME", Column{Title: "SIZE", Width: 6}))
+	m.Focus()
+	m.MoveDown(1)
+	keys(r, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(strings.Repeat("x", 40) + "end")})
+	if rows := strings.Split(m.View(), "\n"); !strings.Contains(rows[2], "xend") {
+		t.Errorf("the editor should fit next to the gutter, the columns and the scrollbar, got\n%s", m.View())
+	}
+	keys(tea.KeyMsg{Type: tea.KeyEsc})
+	if keys(tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyDown}, r); m.mode == modeRename {
+		t.Errorf("the placeholder row shouldn't be renamed")
+	}
+}
+
+func TestExpansionPolicies(t *testing.T) {
+	load := func() Nodes {
+		return Nodes{
+			tn("root", c(tn("a", c(tn("a1", c(tn("a11"))))), tn("b", st(NodeCollapsed), c(tn("b1"))))),
+			tn("other", c(tn("o1"))),
+		}
+	}
+	for _, tc := range []struct {
+		name   string
+		policy ExpansionPolicy
+		want   []string
+	}{
+		{"none", nil, []string{"root", "a", "a1", "a11", "b", "other", "o1"}},
+		{"ExpandAll", ExpandAll, []string{"root", "a", "a1", "a11", "b", "b1", "other", "o1"}},
+		{"CollapseAll", CollapseAll, []string{"root", "other"}},
+		{"ExpandRoots", ExpandRoots, []string{"root", "a", "b", "other", "o1"}},
+		{"ExpandToDepth", ExpandToDepth(2), []string{"root", "a", "a1", "b", "b1", "other", "o1"}},
+		{"custom", func(n Node, _ int) bool { return n.Name() != "a" }, []string{"root", "a", "b", "b1", "other", "o1"}},
+	} {
+		m := New(load(), WithExpansion(tc.policy))
+		if got := names(m.nodes); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: New() shows %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	m := New(load(), WithExpansion(ExpandRoots))
+	m.SetNodes(Nodes{tn("new", c(tn("n1", c(tn("n11")))))})
+	if want := []string{"new", "n1"}; !reflect.DeepEqual(names(m.nodes), want) {
+		t.Errorf("the policy should be applied to the nodes set with SetNodes, got %v", names(m.nodes))
+	}
+}
+
+func TestMoveNode(t *testing.T) {
+	a, b, c := tn("a"), tn("b"), tn("c")
+	dir := &node{name: "dir", children: []*node{a, b, c}}
+	a.parent, b.parent, c.parent = dir, dir, dir
+	roots := Nodes{dir, tn("other")}
+	m := New(roots)
+	m.MoveDown(1)
+
+	if msgs := collect(m.MoveNodeUp()); len(msgs) != 0 {
+		t.Errorf("the first sibling shouldn't be moved up, got %v", msgs)
+	}
+	msgs := collect(m.MoveNodeDown())
+	if want := []string{"dir", "b", "a", "c", "other"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if m.currentNode() != a {
+		t.Errorf("the cursor should follow the moved node, it's on %q", m.currentNode().Name())
+	}
+	if len(msgs) != 2 || msgs[0] != (NodeMovedMsg{Node: a, From: 0, To: 1}) {
+		t.Errorf("expected a NodeMovedMsg, got %v", msgs)
+	}
+	if _, ok := msgs[1].(OrderChangedMsg); !ok {
+		t.Errorf("expected an OrderChangedMsg, got %v", msgs)
+	}
+
+	m.MoveNodeDown()
+	if msgs := collect(m.MoveNodeDown()); len(msgs) != 0 || m.currentNode() != a {
+		t.Errorf("the last sibling shouldn't be moved down, got %v", msgs)
+	}
+	if want := []string{"dir", "b", "c", "a", "other"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+
+	m.setCursor(0)
+	m.MoveNodeDown()
+	if want := []string{"other", "dir", "b", "c", "a"}; !reflect.DeepEqual(names(m.AllNodes()), want) || m.currentNode() != dir {
+		t.Errorf("the top level node should be moved along with its subtree, got %v", names(m.AllNodes()))
+	}
+	if roots[0] != dir {
+		t.Errorf("the slice passed to New shouldn't be reordered")
+	}
+
+	m.SetSortFunc(func(a, b Node) bool { return a.Name() < b.Name() })
+	if msgs := collect(m.MoveNodeUp()); len(msgs) != 0 {
+		t.Errorf("the nodes shouldn't be moved while sorted, got %v", msgs)
+	}
+	if want := []string{"dir", "a", "b", "c", "other"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+
+	x, hidden, y := tn("x"), tn("hidden", st(NodeHidden)), tn("y")
+	dir = &node{name: "dir", children: []*node{x, hidden, y}}
+	x.parent, hidden.parent, y.parent = dir, dir, dir
+	m = New(Nodes{dir})
+	m.MoveDown(1)
+	msgs = collect(m.MoveNodeDown())
+	if want := []string{"hidden", "y", "x"}; !reflect.DeepEqual(names(dir.Children()), want) {
+		t.Errorf("the hidden sibling should stay in front of y, got %v", names(dir.Children()))
+	}
+	if len(msgs) != 2 || msgs[0] != (NodeMovedMsg{Node: x, From: 0, To: 2}) {
+		t.Errorf("expected a NodeMovedMsg, got %v", msgs)
+	}
+}
+
+func TestContextMenu(t *testing.T) {
+	nodes := Nodes{}
+	for i := 0; i < 20; i++ {
+		nodes = append(nodes, tn(fmt.Sprintf("n%02d", i)))
+	}
+	m := New(nodes)
+	m.SetWidth(40)
+	m.SetHeight(6)
+	m.Focus()
+	m.PinNode(nodes[0])
+	m.MoveDown(11)
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(".")})
+	msgs := collect(cmd)
+	msg, ok := msgs[len(msgs)-1].(NodeContextMsg)
+	if !ok || msg.Node != nodes[11] {
+		t.Fatalf("expected a NodeContextMsg for the current node, got %v", msgs)
+	}
+	if rows := strings.Split(m.View(), "\n"); msg.ScreenRow >= len(rows) || !strings.Contains(rows[msg.ScreenRow], "n11") {
+		t.Errorf("the screen row %d should be the row of the node in\n%s", msg.ScreenRow, m.View())
+	}
+
+	m.SetFilterBar(FilterBarTop)
+	m.SetFilterQuery("n1")
+	m.MoveDown(2)
+	msgs = collect(m.OpenContextMenu())
+	msg = msgs[0].(NodeContextMsg)
+	if rows := strings.Split(m.View(), "\n"); msg.ScreenRow >= len(rows) || !strings.Contains(rows[msg.ScreenRow], msg.Node.Name()) {
+		t.Errorf("the screen row %d should count the filter bar, got\n%s", msg.ScreenRow, m.View())
+	}
+}
+
+func TestUndoExpansion(t *testing.T) {
+	a := tn("a", c(tn("a1"), tn("a2")))
+	b := tn("b", c(tn("b1")))
+	m := New(Nodes{tn("root", c(a, b))})
+	m.SetWidth(40)
+	m.SetHeight(10)
+	m.Focus()
+
+	m.MoveDown(2) // a1
+	m.ToggleMark()
+	m.ApplyExpansion(CollapseAll)
+	m.ToggleMark() // root
+	if want := []string{"root"}; !reflect.DeepEqual(names(m.nodes), want) {
+		t.Fatalf("everything should be collapsed, got %v", names(m.nodes))
+	}
+
+	m.UndoExpansion()
+	if want := []string{"root", "a", "a1", "a2", "b", "b1"}; !reflect.DeepEqual(names(m.nodes), want) {
+		t.Errorf("the expansion should be restored, got %v", names(m.nodes))
+	}
+	if !isMarked(m.nodes[0]) || !isMarked(m.nodes[2]) {
+		t.Errorf("the marks should be left alone")
+	}
+
+	m.setCursor(4) // b
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m.setCursor(2) // a1
+	m.ToggleMark()
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("U")})
+	if want := []string{"root", "a", "a1", "a2", "b", "b1"}; !reflect.DeepEqual(names(m.nodes), want) {
+		t.Errorf("collapsing b should be undone, got %v", names(m.nodes))
+	}
+	if isMarked(m.nodes[2]) || !isMarked(m.nodes[0]) {
+		t.Errorf("unmarking after the collapse shouldn't be undone")
+	}
+}
+
+func TestChangedLines(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a", c(tn("a1"), tn("a2"))), tn("b"), tn("c"), tn("d")))})
+	m.SetWidth(40)
+	m.SetHeight(4)
+	m.Focus()
+
+	lines := m.Lines()
+	if len(lines) != 4 || !strings.Contains(lines[0], "root") || !strings.Contains(lines[3], "a2") {
+		t.Fatalf("Lines() = %q", lines)
+	}
+	if changed := m.ChangedLines(); len(changed) != 0 {
+		t.Errorf("nothing should change between the frames, got %v", changed)
+	}
+	if !reflect.DeepEqual(m.Lines(), lines) {
+		t.Errorf("Lines() should be the same for the same frame")
+	}
+
+	m.MoveDown(1)
+	m.View()
+	if changed := m.ChangedLines(); len(changed) != 0 {
+		t.Errorf("View should mark the lines as drawn, got %v", changed)
+	}
+
+	m.ToggleExpand()
+	m.refresh()
+	if changed := m.ChangedLines(); !reflect.DeepEqual(changed, []int{1, 2, 3}) {
+		t.Errorf("collapsing should change the node and the rows below it, got %v", changed)
+	}
+	m.Lines()
+
+	m.ScrollBy(1)
+	if changed := m.ChangedLines(); !reflect.DeepEqual(changed, []int{0, 1, 2, 3}) {
+		t.Errorf("scrolling should change the shifted rows, got %v", changed)
+	}
+}
+
+func TestTranslator(t *testing.T) {
+	translate := func(key string, args ...any) string {
+		return strings.TrimSuffix(fmt.Sprintln(append([]any{"<" + key}, args...)...), "\n") + ">"
+	}
+	m := New(Nodes{}, WithSize(60, 8), WithEmptyMessage(""))
+	m.SetTranslator(translate)
+	if v := m.View(); !strings.Contains(v, "<"+TextNoNodes+">") {
+		t.Errorf("the empty tree message should be translated, got\n%s", v)
+	}
+
+	m = New(Nodes{tn("root", c(tn("empty", st(NodeCollapsible)), tn("dir", st(NodeCollapsed), c(tn("a"), tn("b")))))}, WithSize(60, 8))
+	m.Focus()
+	m.SetTranslator(translate)
+	m.SetChildCount(ChildCountDirect)
+	m.SetFilterBar(FilterBarTop)
+	m.SetStatus(m.Summary())
+	v := m.View()
+	for _, want := range []string{"<" + TextEmpty + ">", "<" + TextChildCount + " 2>", "<" + TextItemCount + " 3>"} {
+		if !strings.Contains(v, want) {
+			t.Errorf("expected %q in\n%s", want, v)
+		}
+	}
+	m.SetFilterQuery("dir")
+	m.SetStatus(m.Summary())
+	v = m.View()
+	for _, want := range []string{"<" + TextFilterPrompt + ">dir", "<" + TextFilterSummary + " dir>"} {
+		if !strings.Contains(v, want) {
+			t.Errorf("expected %q in\n%s", want, v)
+		}
+	}
+
+	run := func(command string) string {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(":")})
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(command)})
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		return m.View()
+	}
+	if v := run("nope"); !strings.Contains(v, "<"+TextUnknownCommand+" nope>") {
+		t.Errorf("the command errors should be translated, got\n%s", v)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if v := run("mark"); !strings.Contains(v, "<"+TextMissingPattern+" mark>") {
+		t.Errorf("the command errors should be translated, got\n%s", v)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m.SetTranslator(nil)
+	if v := run("nope"); !strings.Contains(v, "unknown command: nope") {
+		t.Errorf("the default translator should be restored, got\n%s", v)
+	}
+}
+
+// underlined returns the underlined parts of the rendered row, the way the matches are highlighted in the tests,
+// lipgloss underlines the runes one by one, so the adjacent ones are joined
+func underlined(row string) []string {
+	parts := []string{}
+	on, last := false, false
+	for _, token := range regexp.MustCompile(`\x1b\[[0-9;]*m|[^\x1b]+`).FindAllString(row, -1) {
+		if !strings.HasPrefix(token, "\x1b[") {
+			if on && last {
+				parts[len(parts)-1] += token
+			} else if on {
+				parts = append(parts, token)
+			}
+			last = on
+			continue
+		}
+		params := strings.Split(strings.TrimSuffix(strings.TrimPrefix(token, "\x1b["), "m"), ";")
+		for i := 0; i < len(params); i++ {
+			switch params[i] {
+			case "", "0", "24":
+				on = false
+			case "4":
+				on = true
+			case "38", "48", "58":
+				// the extended colors
+				if i+1 < len(params) && params[i+1] == "5" {
+					i += 2
+				} else {
+					i += 4
+				}
+			}
+		}
+	}
+	return parts
+}
+
+func TestHighlightModes(t *testing.T) {
+	r := lipgloss.NewRenderer(io.Discard)
+	r.SetColorProfile(termenv.ANSI256)
+	m := New(Nodes{tn("root", c(tn("Alpha"), tn("banana"), tn("cab")))}, WithSize(40, 5))
+	m.Styles.MatchHighlight = r.NewStyle().Underline(true)
+	line := func(i int) string { return m.renderNode(m.nodes[i]) }
+	highlighted := func(i int) []string { return underlined(line(i)) }
+
+	m.SetFilterQuery("a")
+	if got := highlighted(1); !reflect.DeepEqual(got, []string{"A", "a"}) {
+		t.Errorf("smart case should ignore the case of a lower case query, got %q", got)
+	}
+	m.SetCaseMode(CaseSensitive)
+	m.SetFilterQuery("a")
+	if got := highlighted(1); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("sensitive case should highlight only the same case, got %q", got)
+	}
+	m.SetCaseMode(CaseSmart)
+	m.SetFilterQuery("A")
+	if got := names(m.nodes); !reflect.DeepEqual(got, []string{"root", "Alpha"}) || !reflect.DeepEqual(highlighted(1), []string{"A"}) {
+		t.Errorf("smart case should respect the case of an upper case query, got %v and %q", got, highlighted(1))
+	}
+
+	m.SetMatcher(FuzzyMatcher{})
+	m.SetFilterQuery("bnn")
+	if got := highlighted(1); !reflect.DeepEqual(got, []string{"b", "n", "n"}) {
+		t.Errorf("fuzzy matches should be highlighted rune by rune, got %q", got)
+	}
+	m.SetMatcher(nil)
+	m.ToggleRegexp()
+	m.SetFilterQuery("an+a$")
+	if got := highlighted(1); !reflect.DeepEqual(got, []string{"ana"}) {
+		t.Errorf("the regexp match should be highlighted, got %q", got)
+	}
+	m.ToggleRegexp()
+
+	m.SetFilterQuery("a")
+	m.setCursor(3)
+	m.ToggleMark()
+	m.setCursor(2)
+	if got := highlighted(2); !reflect.DeepEqual(got, []string{"a", "a", "a"}) {
+		t.Errorf("the matches should be highlighted on the selected row, got %q", got)
+	}
+	if got := highlighted(3); !reflect.DeepEqual(got, []string{"a"}) || !isMarked(m.nodes[3]) {
+		t.Errorf("the matches should be highlighted on the marked row, got %q", got)
+	}
+	if got := highlighted(0); len(got) != 0 {
+		t.Errorf("nothing should be highlighted without a match, got %q", got)
+	}
+
+	width := lipgloss.Width(line(0))
+	m.Alignment = Alignment{Name: lipgloss.Right}
+	row := regexp.MustCompile(`\x1b\[[0-9;]*m`).ReplaceAllString(line(2), "")
+	if lipgloss.Width(row) != width || !strings.HasSuffix(row, "banana") {
+		t.Errorf("the highlighted name should be aligned within the row, got %q", row)
+	}
+}
+
+func TestGotoLine(t *testing.T) {
+	a := tn("a", c(tn("a1"), tn("a2")), st(NodeCollapsed))
+	m := New(Nodes{tn("root", c(a, tn("b"), tn("c")))})
+	m.SetWidth(40)
+	m.SetHeight(5)
+	m.Focus()
+
+	for _, tc := range []struct {
+		line int
+		want string
+	}{
+		{line: 3, want: "b"}, // the children of the collapsed node aren't counted
+		{line: 0, want: "root"},
+		{line: -5, want: "root"},
+		{line: 99, want: "c"},
+		{line: 2, want: "a"},
+	} {
+		m.GotoLine(tc.line)
+		if got := m.currentNode().Name(); got != tc.want {
+			t.Errorf("GotoLine(%d) selected %q, want %q", tc.line, got, tc.want)
+		}
+	}
+
+	m.ToggleExpand()
+	m.refresh()
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune(":")},
+		{Type: tea.KeyRunes, Runes: []rune("4")},
+		{Type: tea.KeyEnter},
+	}
+	for _, k := range keys {
+		m, _ = m.Update(k)
+	}
+	if got := m.currentNode().Name(); got != "a2" || m.mode != modeNormal {
+		t.Errorf("the prompt should jump into the expanded node, got %q", got)
+	}
+}
+
+func TestEscapedNames(t *testing.T) {
+	r := lipgloss.NewRenderer(io.Discard)
+	r.SetColorProfile(termenv.ANSI256)
+	red := tn("\x1b[31mred_directory_with_a_long_name\x1b[0m")
+	link := tn("\x1b]8;;https://example.com\x1b\\linked\x1b]8;;\x1b\\ file")
+	m := New(Nodes{tn("root", c(red, link))}, WithSize(30, 5))
+	m.Styles.MatchHighlight = r.NewStyle().Underline(true)
+	line := func(i int) string { return m.renderNode(m.nodes[i]) }
+
+	for i := range m.nodes {
+		row := line(i)
+		if w := textWidth(row); w != 29 {
+			t.Errorf("row %d is %d wide, want 29: %q", i, w, row)
+		}
+		if plain := stripANSI(row); strings.Contains(plain, "\x1b") {
+			t.Errorf("row %d contains a broken escape sequence: %q", i, row)
+		}
+	}
+	if plain := stripANSI(line(1)); !strings.Contains(plain, "red_direct") || strings.Contains(plain, "long_name") {
+		t.Errorf("the name should be truncated by its visible width, got %q", plain)
+	}
+
+	m.Search("31m")
+	if len(m.Matches()) != 0 {
+		t.Errorf("the escape sequences shouldn't be searched, got %v", names(m.Matches()))
+	}
+	m.Search("linked f")
+	if len(m.Matches()) != 1 || m.Matches()[0] != link {
+		t.Errorf("the text around the hyperlink should be matched as one, got %v", names(m.Matches()))
+	}
+	m.ClearSearch()
+
+	m.SetFilterQuery("dir")
+	if got := underlined(line(1)); !reflect.DeepEqual(got, []string{"dir"}) {
+		t.Errorf("the highlight should be at the offset of the visible text, got %q in %q", got, line(1))
+	}
+	m.SetFilterQuery("ked f")
+	if got := underlined(line(1)); !reflect.DeepEqual(got, []string{"ked f"}) {
+		t.Errorf("the highlight should be at the offset of the visible text, got %q in %q", got, line(1))
+	}
+}
+
 // TODO: good luck with this! :)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "file?", want: []string{"file2", "file4", "file1", "file3", "file5"}},
+		{pattern: "test/example/**", want: []string{"example", "file2", "file4", "lastchild", "file"}},
+		{pattern: "*/example", want: []string{"example"}},
+		{pattern: "**/file", want: []string{"file"}},
+		{pattern: "nothing/**", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		got := []string{}
+		for _, n := range (Nodes{treeOne}).all() {
+			if matchGlob(tt.pattern, n) {
+				got = append(got, n.Name())
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("matchGlob(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+
+	styled := tn("\x1b[32mmain.go\x1b[0m")
+	tn("root", c(tn("\x1b[1mcmd\x1b[0m", c(styled))))
+	for _, pattern := range []string{"*.go", "cmd/main.go"} {
+		if !matchGlob(pattern, styled) {
+			t.Errorf("matchGlob(%q) should ignore the escape sequences of %q", pattern, nodePath(styled))
+		}
+	}
+}
+
+// renderedRows returns the rendered nodes, rendering the ones outside of the viewport
+func renderedRows(m Model) []string {
+	res := make([]string, len(m.nodes))
+	for i := range m.nodes {
+		res[i] = m.line(i)
+	}
+	return res
+}
+
+func names(ns Nodes) []string {
+	res := make([]string, len(ns))
+	for i, n := range ns {
+		res[i] = n.Name()
+	}
+	return res
+}
+
+func TestOrderFollowsChildren(t *testing.T) {
+	m := New(Nodes{treeOne})
+	want := []string{"tmp", "example1", "test", "example", "file2", "file4", "lastchild", "file", "file1", "file3", "file5"}
+	if got := names(m.AllNodes()); !reflect.DeepEqual(got, want) {
+		t.Errorf("AllNodes() = %v, want %v", got, want)
+	}
+}
+
+func TestSetSortFunc(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("b"), tn("c", c(tn("z"), tn("y"))), tn("a")))})
+	m.MoveDown(1)
+	byName := func(a, b Node) bool { return a.Name() < b.Name() }
+
+	cmd := m.SetSortFunc(byName)
+	if want := []string{"root", "a", "b", "c", "y", "z"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Fatalf("sorted AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if cmd == nil {
+		t.Fatal("expected an OrderChangedMsg command")
+	}
+	if msg, ok := cmd().(OrderChangedMsg); !ok || !sameOrder(msg.Nodes, m.AllNodes()) {
+		t.Errorf("expected OrderChangedMsg with the new order, got %#v", msg)
+	}
+	if got := m.currentNode().Name(); got != "b" {
+		t.Errorf("cursor is on %q, want it to stay on %q", got, "b")
+	}
+	if !isLastNode(m.AllNodes()[3]) || isLastNode(m.AllNodes()[1]) {
+		t.Errorf("NodeLastChild not recomputed after sorting")
+	}
+
+	if cmd := m.SetSortFunc(byName); cmd != nil {
+		t.Errorf("expected no command when the order does not change")
+	}
+
+	m.SetSortFunc(nil)
+	if want := []string{"root", "b", "c", "z", "y", "a"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("unsorted AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+func TestSort(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("bb"), tn("ccc", c(tn("zz"), tn("y"), tn("x"))), tn("a"), tn("dd")))})
+	m.MoveDown(2)
+	byLength := func(a, b Node) bool { return len(a.Name()) < len(b.Name()) }
+
+	cmd := m.Sort(byLength)
+	// the siblings of the same length keep their order
+	if want := []string{"root", "a", "bb", "dd", "ccc", "y", "x", "zz"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("sorted AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if _, ok := collect(cmd)[0].(OrderChangedMsg); !ok {
+		t.Errorf("expected an OrderChangedMsg")
+	}
+	if got := m.currentNode().Name(); got != "ccc" {
+		t.Errorf("cursor is on %q, want it to stay on %q", got, "ccc")
+	}
+
+	m.Sort(Reverse(byLength))
+	if want := []string{"root", "ccc", "zz", "y", "x", "bb", "dd", "a"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("reverse sorted AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if got := m.currentNode().Name(); got != "ccc" {
+		t.Errorf("cursor is on %q, want it to stay on %q", got, "ccc")
+	}
+}
+
+func TestSortFuncs(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("b"), tn("C", c(tn("z"), tn("Y"))), tn("a"), tn("D", c(tn("x")))))})
+
+	m.SetSortFunc(ParentsFirst(Reverse(ByName)))
+	if want := []string{"root", "D", "x", "C", "z", "Y", "b", "a"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("sorted AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a", c(tn("b"))), tn("c")))})
+	m.MoveDown(1)
+	m.ToggleExpand()
+	m.refresh()
+	if want := []string{"root", "a", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Fatalf("collapsed AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+
+	m.GotoBottom()
+	m.Undo()
+	if got := m.currentNode().Name(); got != "a" {
+		t.Errorf("after undoing the jump cursor is on %q, want %q", got, "a")
+	}
+	m.Undo()
+	if want := []string{"root", "a", "b", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("after undoing the collapse AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+
+	m.Redo()
+	if want := []string{"root", "a", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("after redo AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+func TestLeafOnly(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a", st(NodeCollapsed), c(tn("b"), tn("c"))), tn("d")))})
+	m.SetLeafOnly(true)
+	if got := m.currentNode().Name(); got != "b" {
+		t.Fatalf("cursor is on %q, want the first leaf %q", got, "b")
+	}
+
+	m.MoveDown(2)
+	if got := m.currentNode().Name(); got != "d" {
+		t.Errorf("cursor is on %q, want %q", got, "d")
+	}
+
+	m.GotoTop()
+	if got := m.currentNode().Name(); got != "b" {
+		t.Errorf("cursor is on %q after GotoTop, want %q", got, "b")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a1", st(NodeCollapsed), c(tn("b1"), tn("c1"))), tn("d1"), tn("e")))})
+	m.MoveDown(1)
+
+	m.Search("1")
+	if want := []string{"a1", "b1", "c1", "d1"}; !reflect.DeepEqual(names(m.Matches()), want) {
+		t.Fatalf("Matches() = %v, want %v", names(m.Matches()), want)
+	}
+	if got := m.currentNode().Name(); got != "a1" {
+		t.Errorf("cursor is on %q, want %q", got, "a1")
+	}
+
+	m.NextMatch()
+	if got := m.currentNode().Name(); got != "b1" {
+		t.Errorf("cursor is on %q, want the collapsed %q to be revealed", got, "b1")
+	}
+
+	m.PrevMatch()
+	m.PrevMatch()
+	if got := m.currentNode().Name(); got != "d1" {
+		t.Errorf("cursor is on %q, want wrapping around to %q", got, "d1")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a", st(NodeCollapsed), c(tn("match"), tn("b"))), tn("c")))})
+	m.GotoBottom()
+
+	m.SetFilter(func(n Node) bool { return n.Name() == "match" })
+	if want := []string{"root", "a", "match"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("filtered AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if got := m.currentNode(); got == nil || !isSelected(got) {
+		t.Errorf("cursor should stay on a visible, selected node, got %v", got)
+	}
+
+	m.SetFilter(func(n Node) bool { return false })
+	if len(m.AllNodes()) != 0 || m.currentNode() != nil {
+		t.Errorf("everything should be hidden, got %v", names(m.AllNodes()))
+	}
+
+	m.ClearFilter()
+	if want := []string{"root", "a", "match", "b", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("unfiltered AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+func TestFilterUndo(t *testing.T) {
+	all := []string{"root", "a", "b", "c"}
+	m := New(Nodes{tn("root", c(tn("a"), tn("b"), tn("c")))})
+
+	m.SetFilterQuery("b")
+	m.ClearFilter()
+	m.Undo()
+	if want := []string{"root", "b"}; !reflect.DeepEqual(names(m.AllNodes()), want) || !m.Filtered() || m.FilterQuery() != "b" {
+		t.Errorf("undoing ClearFilter should bring the filter back, got %v, filtered %v, query %q", names(m.AllNodes()), m.Filtered(), m.FilterQuery())
+	}
+	m.ClearFilter()
+	if !reflect.DeepEqual(names(m.AllNodes()), all) {
+		t.Errorf("the restored filter should be cleared, got %v", names(m.AllNodes()))
+	}
+
+	m.SetFilterQuery("b")
+	m.Undo()
+	if !reflect.DeepEqual(names(m.AllNodes()), all) || m.Filtered() || m.FilterQuery() != "" {
+		t.Errorf("undoing SetFilterQuery should drop the filter, got %v, filtered %v, query %q", names(m.AllNodes()), m.Filtered(), m.FilterQuery())
+	}
+	m.Refresh()
+	if !reflect.DeepEqual(names(m.AllNodes()), all) {
+		t.Errorf("Refresh shouldn't apply the undone filter, got %v", names(m.AllNodes()))
+	}
+}
+
+func TestFilterBar(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("alpha"), tn("beta")))}, WithFilterBar(FilterBarBottom))
+	m.SetHeight(10)
+	m.Focus()
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	for _, r := range "alp" {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	if want := []string{"root", "alpha"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() while typing = %v, want %v", names(m.AllNodes()), want)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.FilterQuery() != "alp" || !strings.Contains(m.View(), "alp") {
+		t.Errorf("filter should stay shown after Enter, query %q", m.FilterQuery())
+	}
+
+	m.Undo()
+	if len(m.AllNodes()) != 3 {
+		t.Errorf("a single undo should drop the whole filter, got %v", names(m.AllNodes()))
+	}
+}
+
+func TestFuzzyMatcher(t *testing.T) {
+	tests := []struct {
+		query, text string
+		ok          bool
+		positions   []int
+	}{
+		{query: "tgo", text: "tree.go", ok: true, positions: []int{0, 5, 6}},
+		{query: "tg", text: "tree.go", ok: true, positions: []int{0, 5}},
+		{query: "og", text: "tree.go", ok: false},
+		{query: "é", text: "café", ok: true, positions: []int{3}},
+	}
+	for _, tt := range tests {
+		got, ok := FuzzyMatcher{}.Match(tt.query, tt.text)
+		if ok != tt.ok || !reflect.DeepEqual(got.Positions, tt.positions) {
+			t.Errorf("Match(%q, %q) = %v, %v, want %v, %v", tt.query, tt.text, got.Positions, ok, tt.positions, tt.ok)
+		}
+	}
+
+	consecutive, _ := FuzzyMatcher{}.Match("tree", "tree.go")
+	scattered, _ := FuzzyMatcher{}.Match("tree", "the_real_estate")
+	if consecutive.Score <= scattered.Score {
+		t.Errorf("consecutive match scored %d, not more than the scattered %d", consecutive.Score, scattered.Score)
+	}
+}
+
+func TestRenderTinyWidths(t *testing.T) {
+	for _, width := range []int{1, 2, 5, 12, 20, 40} {
+		m := New(Nodes{treeOne})
+		m.SetWidth(width)
+		for i := range m.AllNodes() {
+			if line := m.line(i); lipgloss.Width(line) > width {
+				t.Errorf("width %d: line %d is %d wide: %q", width, i, lipgloss.Width(line), line)
+			}
+		}
+	}
+
+	m := New(Nodes{treeOne})
+	m.SetWidth(0)
+	for i := range m.AllNodes() {
+		if line, name := m.line(i), m.AllNodes()[i].Name(); !strings.HasSuffix(line, name) {
+			t.Errorf("without a width line %d should contain the whole name %q: %q", i, name, line)
+		}
+	}
+}
+
+func TestSizeBeforeWindowSizeMsg(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a"), tn("b")))})
+	if m.Width() != DefaultWidth || m.Height() != DefaultHeight {
+		t.Errorf("default size = %dx%d, want %dx%d", m.Width(), m.Height(), DefaultWidth, DefaultHeight)
+	}
+	if view := m.View(); !strings.Contains(view, "b") {
+		t.Errorf("View() before any WindowSizeMsg should show the nodes, got %q", view)
+	}
+
+	m = New(Nodes{tn("root", c(tn("a"), tn("b")))}, WithSize(20, 2))
+	if got := strings.Count(m.View(), "\n") + 1; got != 2 {
+		t.Errorf("View() with WithSize(20, 2) has %d rows, want 2", got)
+	}
+}
+
+func TestFlatResults(t *testing.T) {
+	m := New(Nodes{tn("src", c(tn("tree", st(NodeCollapsed), c(tn("node.go"), tn("tree.go"))), tn("main.go")))}, WithFlatResults())
+	m.Search("node")
+	if want := []string{"node.go"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("results = %v, want %v", names(m.AllNodes()), want)
+	}
+	if view := m.View(); !strings.Contains(view, "src/tree/node.go") {
+		t.Errorf("result should be shown with its path, got %q", view)
+	}
+
+	m.selectResult()
+	if want := []string{"src", "tree", "node.go", "tree.go", "main.go"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() after selecting = %v, want %v", names(m.AllNodes()), want)
+	}
+	if got := m.currentNode(); got == nil || got.Name() != "node.go" {
+		t.Errorf("cursor should be on the selected result, got %v", got)
+	}
+}
+
+// menu mixes a branch with an Activatable leaf
+type menu struct {
+	*node
+	items Nodes
+}
+
+func (m menu) Children() Nodes { return m.items }
+
+type action struct {
+	*node
+	msg tea.Msg
+}
+
+func (a action) Activate() tea.Msg { return a.msg }
+
+func TestActivate(t *testing.T) {
+	root := tn("menu", st(NodeCollapsible))
+	run := action{node: tn("run", p(root), st(NodeLastChild)), msg: "ran"}
+	m := New(Nodes{&menu{node: root, items: Nodes{run}}})
+	m.Focus()
+
+	if cmd := m.Activate(); cmd != nil {
+		t.Errorf("a branch shouldn't be activated")
+	}
+	m.GotoBottom()
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatalf("Enter on an Activatable leaf should emit its message")
+	}
+	if msg := cmd(); msg != "ran" {
+		t.Errorf("activated message = %v, want %q", msg, "ran")
+	}
+}
+
+func TestRelatives(t *testing.T) {
+	leaf := tn("leaf")
+	root := tn("root", c(tn("a", c(leaf, tn("b"))), tn("c")))
+
+	if want := []string{"a", "root"}; !reflect.DeepEqual(names(Ancestors(leaf)), want) {
+		t.Errorf("Ancestors() = %v, want %v", names(Ancestors(leaf)), want)
+	}
+	if want := []string{"a", "leaf", "b", "c"}; !reflect.DeepEqual(names(Descendants(root)), want) {
+		t.Errorf("Descendants() = %v, want %v", names(Descendants(root)), want)
+	}
+	if got := SiblingIndex(root.children[1]); got != 1 {
+		t.Errorf("SiblingIndex() = %d, want 1", got)
+	}
+	if got := SiblingIndex(root); got != -1 {
+		t.Errorf("SiblingIndex() of a top level node = %d, want -1", got)
+	}
+}
+
+func TestQueryHistory(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("alpha"), tn("beta")))})
+	m.Focus()
+	press := func(keys ...tea.KeyMsg) {
+		for _, k := range keys {
+			m, _ = m.Update(k)
+		}
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	enter, up, down := tea.KeyMsg{Type: tea.KeyEnter}, tea.KeyMsg{Type: tea.KeyUp}, tea.KeyMsg{Type: tea.KeyDown}
+
+	press(runes("/"), runes("al"), enter, runes("/"), runes("be"), enter)
+	if want := []string{"al", "be"}; !reflect.DeepEqual(m.QueryHistory(), want) {
+		t.Fatalf("QueryHistory() = %v, want %v", m.QueryHistory(), want)
+	}
+
+	press(runes("/"), runes("x"), up, up)
+	if got := m.prompt.Value(); got != "al" {
+		t.Errorf("recalled %q, want %q", got, "al")
+	}
+	if got := m.currentNode().Name(); got != "alpha" {
+		t.Errorf("recalled query should be searched for, cursor on %q", got)
+	}
+	press(down, down)
+	if got := m.prompt.Value(); got != "x" {
+		t.Errorf("going past the newest query should restore the draft, got %q", got)
+	}
+}
+
+func TestPlaceholder(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("empty", st(NodeCollapsible)), tn("file")))}, WithSize(30, 5))
+	if want := []string{"root", "empty", "(empty)", "file"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if want := []string{"root", "empty", "file"}; !reflect.DeepEqual(names(m.VisibleNodes()), want) {
+		t.Errorf("VisibleNodes() = %v, want %v", names(m.VisibleNodes()), want)
+	}
+	m.MoveDown(2)
+	if m.SelectedNode() != nil || m.CurrentPath() != nil {
+		t.Errorf("placeholders shouldn't be selected, got %v", m.SelectedNode())
+	}
+	m.MoveUp(1)
+	if want := []string{"root", "empty"}; !reflect.DeepEqual(names(m.CurrentPath()), want) {
+		t.Errorf("CurrentPath() = %v, want %v", names(m.CurrentPath()), want)
+	}
+	if m.View(); m.Styles.Placeholder.GetWidth() != 0 || m.Styles.Line.GetWidth() != 0 {
+		t.Errorf("rendering the rows shouldn't change the styles they share")
+	}
+
+	m.SetTranslator(func(key string, args ...any) string {
+		if key == TextEmpty {
+			return ""
+		}
+		return DefaultTranslator(key, args...)
+	})
+	if want := []string{"root", "empty", "file"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() without the placeholder text = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("tree.go"), tn("tree_test.go")))})
+	m.ToggleRegexp()
+	m.SetFilterQuery(`_test\.go$`)
+	if want := []string{"root", "tree_test.go"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+
+	if err := m.validateQuery("("); err == nil {
+		t.Errorf("malformed regexp should be reported")
+	}
+	m.ToggleRegexp()
+	if m.Regexp() || m.validateQuery("(") != nil {
+		t.Errorf("toggling the regexp off should restore the previous matcher")
+	}
+}
+
+func TestSetNodes(t *testing.T) {
+	load := func(names ...string) Nodes {
+		children := []func(*node){}
+		for _, name := range names {
+			children = append(children, c(tn(name, c(tn(name+".child")))))
+		}
+		return Nodes{tn("root", children...)}
+	}
+	m := New(load("a", "b"))
+	m.GotoBottom() // b.child
+	m.MoveUp(2)    // a.child
+	m.MoveUp(1)    // a
+	m.ToggleExpand()
+	m.refresh()
+
+	m, _ = m.Update(RefreshedMsg{Nodes: load("new", "a", "b")})
+	if want := []string{"root", "new", "new.child", "a", "b", "b.child"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if got := m.currentNode(); got == nil || got.Name() != "a" || !isSelected(got) {
+		t.Errorf("cursor should stay on the replaced node, got %v", got)
+	}
+
+	m.SetNodes(Nodes{tn("root", c(tn("b")))})
+	m.GotoBottom()
+	m.SetNodes(Nodes{tn("root", c(tn("c")))})
+	if got := m.currentNode(); got == nil || got.Name() != "root" {
+		t.Errorf("cursor should move to the closest remaining ancestor, got %v", got)
+	}
+}
+
+func TestAutoRefresh(t *testing.T) {
+	m := New(Nodes{tn("root")})
+	loads := 0
+	tick := m.AutoRefresh(time.Hour, func() tea.Cmd {
+		loads++
+		return nil
+	})
+	if tick == nil {
+		t.Fatalf("AutoRefresh should start ticking")
+	}
+
+	id := m.autoRefresh.id
+	m, _ = m.Update(autoRefreshMsg{id: id})
+	m, _ = m.Update(autoRefreshMsg{id: id})
+	if loads != 1 {
+		t.Errorf("a load shouldn't start before the previous one is done, got %d loads", loads)
+	}
+	m, _ = m.Update(RefreshedMsg{})
+	m, _ = m.Update(autoRefreshMsg{id: id})
+	if loads != 2 {
+		t.Errorf("loads = %d, want 2", loads)
+	}
+
+	m.AutoRefresh(0, nil)
+	m, _ = m.Update(RefreshedMsg{})
+	m, _ = m.Update(autoRefreshMsg{id: id})
+	if loads != 2 {
+		t.Errorf("stopped refresh shouldn't load, got %d loads", loads)
+	}
+}
+
+func TestHiddenPredicate(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn(".git", c(tn("config"))), tn("main.go")))})
+	m.GotoLine(3) // config
+	dotfiles := func(n Node) bool { return strings.HasPrefix(n.Name(), ".") }
+
+	m.SetHiddenPredicate(dotfiles)
+	if want := []string{"root", "main.go"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if got := m.currentNode(); got == nil || !isSelected(got) {
+		t.Errorf("cursor should move onto a visible node, got %v", got)
+	}
+
+	m.ToggleHidden()
+	if want := []string{"root", ".git", "config", "main.go"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() while showing hidden = %v, want %v", names(m.AllNodes()), want)
+	}
+
+	m.ApplyExpansion(ExpandAll)
+	m.ToggleHidden()
+	m.Undo()
+	if want := []string{"root", "main.go"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("Undo shouldn't show the nodes hidden by the predicate, got %v", names(m.AllNodes()))
+	}
+	m.ToggleHidden()
+	m.Redo()
+	if want := []string{"root", ".git", "config", "main.go"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("Redo shouldn't hide the nodes shown by ToggleHidden, got %v", names(m.AllNodes()))
+	}
+}
+
+func TestFilterDebounce(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("alpha"), tn("beta")))}, WithFilterBar(FilterBarTop))
+	m.FilterDebounce = time.Hour
+	m.Focus()
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")})
+	stale := m.filterSeq
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	if len(m.AllNodes()) != 3 || !strings.Contains(m.View(), "filtering…") {
+		t.Errorf("filtering should wait for the typing to pause, got %v", names(m.AllNodes()))
+	}
+
+	m, _ = m.Update(filterDebouncedMsg{seq: stale})
+	if len(m.AllNodes()) != 3 {
+		t.Errorf("stale ticks should be ignored, got %v", names(m.AllNodes()))
+	}
+	m, _ = m.Update(filterDebouncedMsg{seq: m.filterSeq})
+	if want := []string{"root", "alpha"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() after the pause = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+type pod struct {
+	*node
+	severity Severity
+}
+
+func (p pod) Severity() Severity { return p.severity }
+
+func TestSeverityRollup(t *testing.T) {
+	root := tn("cluster", st(NodeCollapsible|NodeCollapsed))
+	failing := pod{node: tn("failing", p(root)), severity: SeverityError}
+	healthy := pod{node: tn("healthy", p(root)), severity: SeverityOK}
+	m := New(Nodes{&menu{node: root, items: Nodes{healthy, failing}}})
+
+	if got := m.Severity(m.tree[0]); got != SeverityNone {
+		t.Errorf("Severity() without the roll-up = %v, want %v", got, SeverityNone)
+	}
+	m.SetSeverityRollup(true)
+	if got := m.Severity(m.tree[0]); got != SeverityError {
+		t.Errorf("rolled up Severity() = %v, want %v", got, SeverityError)
+	}
+	if got := m.Severity(healthy); got != SeverityOK {
+		t.Errorf("Severity() of a leaf = %v, want %v", got, SeverityOK)
+	}
+}
+
+func TestRowActions(t *testing.T) {
+	edit, remove := RowAction{Name: "edit", Icon: "e"}, RowAction{Name: "delete", Icon: "x"}
+	m := New(Nodes{tn("root", c(tn("a"), tn("b")))}, WithRowActions(edit, remove), WithSize(20, 5))
+	m.Focus()
+
+	tab := tea.KeyMsg{Type: tea.KeyTab}
+	m, _ = m.Update(tab)
+	m, _ = m.Update(tab)
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatalf("Enter on a focused action should emit a RowActionMsg")
+	}
+	if msg, ok := cmd().(RowActionMsg); !ok || msg.Action != remove || msg.Node.Name() != "root" {
+		t.Errorf("emitted %v, want the delete action of root", msg)
+	}
+	if w := lipgloss.Width(m.line(0)); w > 20 {
+		t.Errorf("row with the actions is %d wide, more than the width of 20", w)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if _, ok := m.FocusedAction(); ok {
+		t.Errorf("moving the cursor should drop the focus of the action")
+	}
+}
+
+func TestSearchTarget(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a"), tn("b")))})
+	if m.Search("rwx"); len(m.Matches()) != 0 {
+		t.Errorf("only the names should be searched by default, got %v", names(m.Matches()))
+	}
+	m.SetSearchTarget(SearchName | SearchPrefix)
+	if m.Search("rwx"); len(m.Matches()) != 3 {
+		t.Errorf("the prefixes should be searched too, got %v", names(m.Matches()))
+	}
+}
+
+func TestIdleUpdates(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a"), tn("b")))}, WithSize(20, 5))
+	m.Focus()
+
+	idle := []tea.Msg{
+		tea.KeyMsg{Type: tea.KeyUp},                        // already at the top
+		tea.KeyMsg{Type: tea.KeyHome},                      // same
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}, // unbound
+		tea.WindowSizeMsg{Width: 20, Height: 5},            // unchanged
+	}
+	renders = 0
+	for _, msg := range idle {
+		m, _ = m.Update(msg)
+	}
+	m.Blur()
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if renders != 0 {
+		t.Errorf("messages which don't change anything rendered %d times", renders)
+	}
+
+	m.Focus()
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if renders == 0 || renders > 2 {
+		t.Errorf("moving the cursor should re-render only the two rows, got %d renders", renders)
+	}
+}
+
+func TestCaseMode(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("README.md"), tn("readme.txt")))})
+	if m.Search("readme"); len(m.Matches()) != 2 {
+		t.Errorf("lower case query should ignore the case, got %v", names(m.Matches()))
+	}
+	if m.Search("README"); len(m.Matches()) != 1 {
+		t.Errorf("upper case query should be case sensitive, got %v", names(m.Matches()))
+	}
+	m.SetCaseMode(CaseSensitive)
+	if m.Search("readme"); len(m.Matches()) != 1 || m.searchPrompt() != "[Aa]/" {
+		t.Errorf("CaseSensitive matched %v, prompt %q", names(m.Matches()), m.searchPrompt())
+	}
+}
+
+func TestStatusLine(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a1"), tn("b"), tn("a2")))}, WithSize(20, 6))
+	m.Search("a")
+	m.NextMatch()
+	if view := m.View(); !strings.Contains(view, "match 2/2") {
+		t.Errorf("status line should show the position among the matches, got %q", view)
+	}
+	if got := strings.Count(m.View(), "\n") + 1; got != 6 {
+		t.Errorf("View() with the status line has %d rows, want 6", got)
+	}
+
+	m.ClearSearch()
+	if strings.Contains(m.View(), "match") {
+		t.Errorf("status line should be gone with the query")
+	}
+}
+
+type styled struct {
+	*node
+}
+
+func (styled) Style() lipgloss.Style { return lipgloss.NewStyle().Bold(true) }
+
+func TestNodeStyler(t *testing.T) {
+	root := tn("root", st(NodeCollapsible))
+	dir := styled{tn("dir", p(root))}
+	m := New(Nodes{&menu{node: root, items: Nodes{dir}}})
+
+	if style := m.nodeStyle(dir); !style.GetBold() || style.GetReverse() {
+		t.Errorf("style of the node should be used as is")
+	}
+	m.GotoBottom()
+	if style := m.nodeStyle(dir); !style.GetBold() || !style.GetReverse() {
+		t.Errorf("selected style should be composed with the style of the node")
+	}
+}
+
+type iconic struct {
+	*node
+}
+
+func (iconic) Icon() string { return "📁" }
+
+func TestNodeIcon(t *testing.T) {
+	root := tn("root", st(NodeCollapsible))
+	dir := iconic{tn("directory", p(root))}
+	m := New(Nodes{&menu{node: root, items: Nodes{dir}}}, WithSize(40, 5))
+
+	row := m.line(1)
+	if !strings.Contains(row, "📁 directory") {
+		t.Errorf("icon should be rendered in front of the name, got %q", row)
+	}
+	if w := lipgloss.Width(row); w != 39 {
+		t.Errorf("row with a wide icon is %d wide, want 39", w)
+	}
+}
+
+func TestIndicators(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("dir", st(NodeCollapsed), c(tn("file"))), tn("file")))})
+	if !strings.Contains(m.line(1), "▸ dir") || !strings.Contains(m.line(2), "  file") {
+		t.Errorf("collapsed node should have an indicator, the leaves padding, got %q", renderedRows(m)[1:])
+	}
+
+	m.Indicators.Placement = IndicatorBeforePrefix
+	m.refresh()
+	if !strings.HasPrefix(m.line(1), "▸ -rwx") {
+		t.Errorf("indicator should be rendered before the prefix, got %q", m.line(1))
+	}
+
+	m.Indicators.Placement = IndicatorNone
+	m.refresh()
+	if strings.Contains(m.line(1), "▸") {
+		t.Errorf("suppressed indicators shouldn't be rendered, got %q", m.line(1))
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	s := Symbols{Connector: "│  ", Starter: "├", Terminator: "└", Horizontal: "─"}.Normalized()
+	if s.Starter != "├──" || s.Terminator != "└──" || s.Padding != "   " {
+		t.Errorf("Normalized() = %+v", s)
+	}
+
+	m := New(Nodes{tn("root")})
+	if err := m.SetSymbols(Symbols{Starter: "├\n"}); err != ErrMultilineSymbol {
+		t.Errorf("SetSymbols() with a multiline glyph = %v, want %v", err, ErrMultilineSymbol)
+	}
+}
+
+func TestASCIISymbols(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a"), tn("b")))})
+	m.Indicators = Indicators{Expanded: "v", Collapsed: ">"}
+	if err := m.SetSymbols(ASCIISymbols()); err != nil {
+		t.Fatalf("SetSymbols() = %v", err)
+	}
+	for _, line := range renderedRows(m) {
+		for _, r := range line {
+			if r > 127 {
+				t.Errorf("non-ASCII %q in %q", r, line)
+			}
+		}
+	}
+}
+
+func TestDepthPalette(t *testing.T) {
+	red, green := lipgloss.Color("1"), lipgloss.Color("2")
+	palette := DepthPalette(red, green)
+	if palette(0).GetForeground() != red || palette(1).GetForeground() != green || palette(2).GetForeground() != red {
+		t.Errorf("palette should cycle through the colors by depth")
+	}
+
+	depths := []int{}
+	m := New(Nodes{tn("root", c(tn("a", c(tn("b")))))})
+	m.Styles.SymbolByDepth = func(depth int) lipgloss.Style {
+		depths = append(depths, depth)
+		return lipgloss.NewStyle()
+	}
+	m.refresh()
+	if len(depths) == 0 {
+		t.Errorf("SymbolByDepth should be used for the symbols")
+	}
+}
+
+func TestStriping(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a"), tn("b"), tn("c")))}, WithStriping())
+	m.Styles.AltLine = lipgloss.NewStyle().Italic(true)
+	m.refresh()
+	for i, n := range m.AllNodes() {
+		if got := m.baseStyle(n).GetItalic(); got != (i%2 == 1) {
+			t.Errorf("row %d striped = %v", i, got)
+		}
+	}
+}
+
+func TestFullWidthSelection(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a")))}, WithSize(30, 5), WithFullWidthSelection())
+	if w := lipgloss.Width(m.line(0)); w != 30 {
+		t.Errorf("selected row is %d wide, want 30", w)
+	}
+	if w := lipgloss.Width(m.line(1)); w != 29 {
+		t.Errorf("other rows are %d wide, want 29", w)
+	}
+}
+
+func TestLineNumbers(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a"), tn("b")))}, WithSize(30, 5), WithLineNumbers(LineNumbersRelative))
+	m.MoveDown(1)
+	rows := strings.Split(m.gutterView(), "\n")
+	want := []string{"1 ", "2 ", "1 ", "  ", "  "}
+	for i := range want {
+		if got := ansiSequence.ReplaceAllString(rows[i], ""); got != want[i] {
+			t.Errorf("row %d: got gutter %q, want %q", i, got, want[i])
+		}
+	}
+	if w := lipgloss.Width(m.View()); w != 30 {
+		t.Errorf("the view is %d wide, want 30", w)
+	}
+}
+
+func TestChildCount(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("src", st(NodeCollapsed), c(tn("a", c(tn("b"))), tn("c")))))}, WithSize(30, 5), WithChildCount(ChildCountDirect))
+	if got := ansiSequence.ReplaceAllString(m.line(1), ""); !strings.Contains(got, "src (2)") {
+		t.Errorf("expected a badge with the direct children, got %q", got)
+	}
+	if got := ansiSequence.ReplaceAllString(m.line(0), ""); strings.Contains(got, "(") {
+		t.Errorf("expected no badge on an expanded node, got %q", got)
+	}
+	m.SetChildCount(ChildCountRecursive)
+	if got := ansiSequence.ReplaceAllString(m.line(1), ""); !strings.Contains(got, "src (3)") {
+		t.Errorf("expected a badge with all of the descendants, got %q", got)
+	}
+	if w := lipgloss.Width(m.line(1)); w != 29 {
+		t.Errorf("the row is %d wide, want 29", w)
+	}
+}
+
+type described struct {
+	*node
+	desc string
+}
+
+func (d described) Description() string { return d.desc }
+
+func TestDescription(t *testing.T) {
+	root := tn("root", st(NodeCollapsible))
+	first := described{tn("first", p(root)), "the first one"}
+	last := described{tn("last", p(root), st(NodeLastChild)), "the last one"}
+	m := New(Nodes{&menu{node: root, items: Nodes{first, last}}}, WithSize(40, 4), WithLineNumbers(LineNumbersAbsolute))
+
+	if got := lipgloss.Height(m.line(1)); got != 2 {
+		t.Fatalf("described node takes %d rows, want 2", got)
+	}
+	rows := strings.Split(ansiSequence.ReplaceAllString(m.line(1), ""), "\n")
+	col := func(row, s string) int { return lipgloss.Width(row[:strings.Index(row, s)]) }
+	if col(rows[1], "the first one") != col(rows[0], "first") {
+		t.Errorf("description should be aligned with the name, got %q", rows)
+	}
+	if !strings.Contains(rows[1], "│") {
+		t.Errorf("connector should continue to the next sibling, got %q", rows[1])
+	}
+	if strings.Contains(ansiSequence.ReplaceAllString(m.line(2), ""), "│") {
+		t.Errorf("connector shouldn't continue below the last child, got %q", m.line(2))
+	}
+
+	m.MoveDown(2)
+	if m.currentNode() != last {
+		t.Errorf("cursor should move by nodes, not rows, got %v", m.currentNode().Name())
+	}
+	if m.YOffset() != 1 {
+		t.Errorf("viewport should scroll to show both rows of the last node, got offset %d", m.YOffset())
+	}
+	gutter := strings.Split(ansiSequence.ReplaceAllString(m.gutterView(), ""), "\n")
+	if want := []string{"2 ", "  ", "3 ", "  "}; strings.Join(gutter, "|") != strings.Join(want, "|") {
+		t.Errorf("got gutter %q, want %q", gutter, want)
+	}
+}
+
+func TestMultiLineName(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("line one\nline two\nline three"), tn("next")))}, WithSize(40, 3))
+	if got := lipgloss.Height(m.line(1)); got != 3 {
+		t.Fatalf("node with a three line name takes %d rows, want 3", got)
+	}
+	rows := strings.Split(ansiSequence.ReplaceAllString(m.line(1), ""), "\n")
+	for _, row := range rows[1:] {
+		if !strings.Contains(row, "│") || strings.Contains(row, "-rwx") {
+			t.Errorf("continuation row should continue the connectors only, got %q", row)
+		}
+	}
+
+	m.MoveDown(2)
+	if m.currentNode().Name() != "next" {
+		t.Errorf("cursor should skip over the rows of the multi-line node, got %q", m.currentNode().Name())
+	}
+	if m.YOffset() != 2 {
+		t.Errorf("viewport should scroll by rows, got offset %d", m.YOffset())
+	}
+	m.MoveUp(1)
+	if m.YOffset() != 1 {
+		t.Errorf("viewport should show the first row of the multi-line node, got offset %d", m.YOffset())
+	}
+}
+
+func TestWrap(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a rather long name which doesn't fit"), tn("next")))}, WithSize(30, 5), WithWrap())
+	rows := strings.Split(ansiSequence.ReplaceAllString(m.line(1), ""), "\n")
+	if len(rows) < 2 {
+		t.Fatalf("long name should be wrapped, got %q", rows)
+	}
+	if strings.Contains(m.line(1), Ellipsis) {
+		t.Errorf("wrapped name shouldn't be truncated, got %q", rows)
+	}
+	for _, row := range rows {
+		if w := lipgloss.Width(row); w > 29 {
+			t.Errorf("wrapped row is %d wide, want at most 29: %q", w, row)
+		}
+	}
+	if !strings.Contains(rows[0], "a rather") || !strings.Contains(rows[len(rows)-1], "doesn't fit") {
+		t.Errorf("wrapped rows should contain the whole name, got %q", rows)
+	}
+}
+
+func TestTruncation(t *testing.T) {
+	tests := []struct {
+		t    Truncation
+		want string
+	}{
+		{DefaultTruncation(), "src/tree/n…"},
+		{Truncation{Ellipsis: "...", At: TruncateHead}, ".../node.go"},
+		{Truncation{Ellipsis: "~", At: TruncateMiddle}, "src/t~de.go"},
+		{Truncation{At: TruncateTail}, "src/tree/no"},
+	}
+	for _, tt := range tests {
+		if got := tt.t.shorten("src/tree/node.go", 11); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestMixedWidths(t *testing.T) {
+	names := []string{
+		"日本語のとても長いファイル名です.txt",
+		"emoji 🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉",
+		"family 👨‍👩‍👧 family 👨‍👩‍👧 family 👨‍👩‍👧",
+		"ascii",
+	}
+	for _, width := range []int{24, 25} {
+		children := []*node{}
+		for _, name := range names {
+			children = append(children, tn(name))
+		}
+		m := New(Nodes{tn("根", c(children...))}, WithSize(width, 10))
+		for i, line := range renderedRows(m) {
+			if w := lipgloss.Width(line); w != width-1 {
+				t.Errorf("width %d, row %d is %d wide, want %d: %q", width, i, w, width-1, line)
+			}
+			if strings.Contains(line, "‍…") || strings.HasSuffix(strings.TrimRight(line, " "), "‍") {
+				t.Errorf("width %d, row %d cuts a cluster in half: %q", width, i, line)
+			}
+		}
+	}
+
+	tr := Truncation{Ellipsis: "…", At: TruncateMiddle}
+	for _, width := range []int{5, 6, 7, 8} {
+		if got := tr.shorten("日本語のファイル", width); textWidth(got) > width {
+			t.Errorf("%q is wider than %d", got, width)
+		}
+	}
+}
+
+type sized struct {
+	*node
+	prefix string
+}
+
+func (s sized) Prefix() string { return s.prefix }
+
+func TestAlignedPrefixes(t *testing.T) {
+	root := tn("root", st(NodeCollapsible))
+	a := sized{tn("a", p(root)), "-rw-r--r-- 1000:1000 4.0K"}
+	b := sized{tn("b", p(root), st(NodeLastChild)), "drwxr-xr-x 0:0 12M"}
+	m := New(Nodes{&menu{node: root, items: Nodes{a, b}}}, WithSize(60, 5), WithAlignedPrefixes())
+
+	symbols := func(row int) int {
+		line := ansiSequence.ReplaceAllString(m.line(row), "")
+		return lipgloss.Width(line[:strings.IndexAny(line, "├└")])
+	}
+	if symbols(1) != symbols(2) {
+		t.Errorf("connectors should be aligned, got %q", renderedRows(m)[1:])
+	}
+	if !strings.Contains(m.line(2), "drwxr-xr-x 0:0       12M") {
+		t.Errorf("columns should be padded to the widest value, got %q", m.line(2))
+	}
+
+	m.SetPrefixColumns(10, 12, 5)
+	if !strings.Contains(m.line(1), "-rw-r--r-- 1000:1000    4.0K ") {
+		t.Errorf("columns should have the given widths, got %q", m.line(1))
+	}
+}
+
+type process struct {
+	*node
+	cpu, mem string
+}
+
+func (p process) Columns() []string { return []string{p.cpu, p.mem} }
+
+func TestColumns(t *testing.T) {
+	root := tn("init", st(NodeCollapsible))
+	a := process{tn("sshd", p(root)), "0.1", "12M"}
+	b := process{tn("bash", p(root), st(NodeLastChild)), "12.5", "4M"}
+	m := New(Nodes{&menu{node: root, items: Nodes{a, b}}}, WithSize(40, 3),
+		WithColumns("NAME", Column{Title: "CPU", Align: lipgloss.Right}, Column{Title: "MEM", Width: 5}))
+
+	view := strings.Split(ansiSequence.ReplaceAllString(m.View(), ""), "\n")
+	if len(view) != 3 {
+		t.Fatalf("header should take a row of the height, got %d rows", len(view))
+	}
+	if header := ansiSequence.ReplaceAllString(m.headerView(), ""); !strings.HasPrefix(header, "NAME") || !strings.HasSuffix(header, "  CPU MEM  ") {
+		t.Errorf("unexpected header %q", header)
+	}
+	rows := strings.Split(ansiSequence.ReplaceAllString(strings.Join(renderedRows(m), "\n"), ""), "\n")
+	if !strings.HasSuffix(rows[1], "  0.1 12M  ") || !strings.HasSuffix(rows[2], " 12.5 4M   ") {
+		t.Errorf("values should be aligned into the columns, got %q", rows[1:])
+	}
+	for i, row := range renderedRows(m) {
+		if w := lipgloss.Width(row); w != 39 {
+			t.Errorf("row %d is %d wide, want 39", i, w)
+		}
+	}
+
+	m.MoveDown(2)
+	if m.YOffset() != 1 || !strings.HasPrefix(ansiSequence.ReplaceAllString(m.View(), ""), "NAME") {
+		t.Errorf("header should stay in place while scrolling")
+	}
+}
+
+type file struct {
+	*node
+	size string
+}
+
+func (f file) Suffix() string { return f.size }
+
+func TestSuffix(t *testing.T) {
+	root := tn("root", st(NodeCollapsible))
+	f := file{tn("a file with a rather long name", p(root), st(NodeLastChild)), "4.0K"}
+	m := New(Nodes{&menu{node: root, items: Nodes{f}}}, WithSize(40, 3))
+
+	row := ansiSequence.ReplaceAllString(m.line(1), "")
+	if !strings.HasSuffix(row, "… 4.0K") {
+		t.Errorf("suffix should be right-aligned with the name truncated, got %q", row)
+	}
+	if w := lipgloss.Width(m.line(1)); w != 39 {
+		t.Errorf("row is %d wide, want 39", w)
+	}
+}
+
+func TestScrollbar(t *testing.T) {
+	children := []*node{}
+	for i := 0; i < 19; i++ {
+		children = append(children, tn(fmt.Sprintf("child %d", i)))
+	}
+	m := New(Nodes{tn("root", c(children...))}, WithSize(30, 5), WithScrollbar())
+
+	thumbAt := func() []int {
+		rows := []int{}
+		for i, row := range strings.Split(m.scrollbarView(), "\n") {
+			if strings.Contains(row, m.Scrollbar.Thumb) {
+				rows = append(rows, i)
+			}
+		}
+		return rows
+	}
+	if got := thumbAt(); len(got) != 1 || got[0] != 0 {
+		t.Errorf("thumb should be a single row at the top, got %v", got)
+	}
+	m.GotoBottom()
+	if got := thumbAt(); len(got) != 1 || got[0] != 4 {
+		t.Errorf("thumb should be at the bottom, got %v", got)
+	}
+	for i, row := range strings.Split(m.View(), "\n") {
+		if w := lipgloss.Width(row); w != 30 {
+			t.Errorf("row %d of the view is %d wide, want 30", i, w)
+		}
+	}
+}
+
+func TestStickyAncestors(t *testing.T) {
+	leaves := []*node{}
+	for i := 0; i < 10; i++ {
+		leaves = append(leaves, tn(fmt.Sprintf("leaf %d", i)))
+	}
+	m := New(Nodes{tn("root", c(tn("dir", c(leaves...)), tn("last")))}, WithSize(30, 4), WithStickyAncestors(2))
+
+	m.MoveDown(6)
+	view := strings.Split(ansiSequence.ReplaceAllString(m.View(), ""), "\n")
+	if !strings.Contains(view[0], "root") || !strings.Contains(view[1], "dir") {
+		t.Errorf("ancestors should stick to the top, got %q", view)
+	}
+	if !strings.Contains(view[3], "leaf 4") {
+		t.Errorf("cursor should stay visible below the sticky ancestors, got %q", view)
+	}
+
+	m.MoveUp(2)
+	view = strings.Split(ansiSequence.ReplaceAllString(m.View(), ""), "\n")
+	if !strings.Contains(view[2], "leaf 2") {
+		t.Errorf("cursor shouldn't hide under the sticky ancestors, got %q", view)
+	}
+}
+
+func TestTitleAndStatus(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a.go"), tn("b.md")))}, WithSize(40, 6))
+	m.SetTitle("/home/user/src")
+	m.SetStatus(m.Summary())
+
+	view := strings.Split(ansiSequence.ReplaceAllString(m.View(), ""), "\n")
+	if len(view) != 6 {
+		t.Fatalf("view should keep its height, got %d rows", len(view))
+	}
+	if !strings.HasPrefix(view[0], "/home/user/src") {
+		t.Errorf("title bar should be the first row, got %q", view[0])
+	}
+	if got := strings.TrimSpace(view[5]); got != "3 items · 100%" {
+		t.Errorf("unexpected status %q", got)
+	}
+
+	m.Search("go")
+	view = strings.Split(ansiSequence.ReplaceAllString(m.View(), ""), "\n")
+	if got := strings.TrimSpace(view[5]); got != "3 items · 100% · match 1/1" {
+		t.Errorf("status should be followed by the matches, got %q", got)
+	}
+
+	m = New(Nodes{tn("root", c(tn("a"), tn("b"), tn("c"))), tn("empty", st(NodeCollapsible))}, WithSize(40, 8), WithChunkSize(2))
+	if got := m.Summary(); !strings.HasPrefix(got, "4 items") {
+		t.Errorf("the load more and the placeholder rows shouldn't be counted, got %q", got)
+	}
+}
+
+func TestThemes(t *testing.T) {
+	themes := map[string]Styles{
+		"monochrome":    MonochromeStyles(),
+		"high contrast": HighContrastStyles(),
+		"dracula":       DraculaStyles(),
+		"nord":          NordStyles(),
+	}
+	for name, styles := range themes {
+		m := New(Nodes{tn("root", c(tn("a"), tn("b")))}, WithSize(30, 5), WithStyles(styles), WithStriping())
+		for i, row := range renderedRows(m) {
+			if w := lipgloss.Width(row); w != 29 {
+				t.Errorf("%s: row %d is %d wide, want 29", name, i, w)
+			}
+		}
+	}
+	if _, ok := MonochromeStyles().Error.GetForeground().(lipgloss.NoColor); !ok {
+		t.Errorf("monochrome preset shouldn't use any colors")
+	}
+}
+
+func TestRenderer(t *testing.T) {
+	r := lipgloss.NewRenderer(io.Discard)
+	r.SetColorProfile(termenv.ANSI256)
+	m := New(Nodes{tn("root", c(tn("a")))}, WithSize(30, 5), WithStyles(NordStyles()), WithRenderer(r))
+	if !strings.Contains(m.line(0), "\x1b[") {
+		t.Errorf("styles should be rendered with the colors of the renderer's profile, got %q", m.line(0))
+	}
+
+	r.SetColorProfile(termenv.Ascii)
+	m.refresh()
+	if strings.Contains(m.line(0), "\x1b[") {
+		t.Errorf("styles shouldn't be rendered with colors for the ascii profile, got %q", m.line(0))
+	}
+}
+
+func TestEmptyTree(t *testing.T) {
+	m := New(Nodes{}, WithSize(20, 3), WithEmptyMessage("empty directory"))
+	m.Focus()
+	for _, k := range []string{"j", "k", "enter", "G", " "} {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)})
+	}
+	rows := strings.Split(ansiSequence.ReplaceAllString(m.View(), ""), "\n")
+	if len(rows) != 3 || strings.TrimSpace(rows[0]) != "empty directory" {
+		t.Errorf("the message should be shown in place of the tree, got %q", rows)
+	}
+
+	m.SetNodes(Nodes{tn("root", c(tn("a")))})
+	if got := m.currentNode(); got == nil || got.Name() != "root" || !isSelected(got) {
+		t.Errorf("the first node should be selected once the nodes are set, got %v", got)
+	}
+	if strings.Contains(m.View(), "empty directory") {
+		t.Errorf("the message shouldn't be shown with nodes in the tree")
+	}
+}
+
+func TestLoading(t *testing.T) {
+	m := New(Nodes{}, WithSize(20, 3), WithLoadingMessage("fetching"))
+	cmd := m.SetLoading(true)
+	if cmd == nil {
+		t.Fatalf("turning the loading on should start the spinner")
+	}
+	if m, cmd = m.Update(cmd()); cmd == nil {
+		t.Errorf("the spinner should keep ticking while loading")
+	}
+	rows := strings.Split(ansiSequence.ReplaceAllString(m.View(), ""), "\n")
+	if len(rows) != 3 || !strings.Contains(rows[1], "fetching") {
+		t.Errorf("the spinner should be centered in place of the tree, got %q", rows)
+	}
+
+	m.SetNodes(Nodes{tn("root")})
+	if m.Loading() || !strings.Contains(m.View(), "root") {
+		t.Errorf("setting the nodes should show the tree, got %q", m.View())
+	}
+	if _, cmd = m.Update(spinner.TickMsg{ID: m.spinner.ID()}); cmd != nil {
+		t.Errorf("the spinner should stop once loaded")
+	}
+}
+
+func TestMutations(t *testing.T) {
+	root := tn("root", c(tn("a"), tn("c")))
+	m := New(Nodes{root})
+	m.MoveDown(1)
+
+	if !m.InsertNode(root, 1, tn("b", p(root), c(tn("b1")))) {
+		t.Fatalf("the node should be inserted")
+	}
+	if want := []string{"root", "a", "b", "b1", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() after the insert = %v, want %v", names(m.AllNodes()), want)
+	}
+	if got := m.currentNode(); got.Name() != "a" || !isSelected(got) {
+		t.Errorf("the cursor should stay on the same node, got %q", got.Name())
+	}
+	if last := m.AllNodes()[4]; !isLastNode(last) || isLastNode(m.AllNodes()[2]) {
+		t.Errorf("only the last sibling should be marked as such")
+	}
+
+	m.MoveDown(1)
+	if !m.RemoveNode(m.currentNode()) {
+		t.Fatalf("the node should be removed")
+	}
+	if want := []string{"root", "a", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() after the removal = %v, want %v", names(m.AllNodes()), want)
+	}
+	if got := m.currentNode(); got.Name() != "c" || !isSelected(got) {
+		t.Errorf("the cursor should stay on the same row, got %q", got.Name())
+	}
+
+	m.ReplaceChildren(nil, Nodes{tn("x"), tn("y")})
+	if want := []string{"x", "y"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() after replacing the top level = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+// identified is a node with a stable identity, see Identifiable
+type identified struct {
+	*node
+	id string
+}
+
+var _ Identifiable = identified{}
+
+func (n identified) ID() string { return n.id }
+
+func TestViewState(t *testing.T) {
+	load := func(name string) Nodes {
+		root := tn("root")
+		dir := identified{tn(name, p(root), st(NodeCollapsible), c(tn("file"))), "dir-1"}
+		root.children = []*node{dir.node}
+		return Nodes{tn("top", c(tn("leaf"))), dir}
+	}
+	m := New(load("old name"))
+	m.MoveDown(1) // leaf
+	m.ToggleMark()
+	m.PinNode(m.currentNode())
+	m.MoveDown(1)
+	m.ToggleExpand()
+	m.refresh()
+	saved := m.ViewState()
+
+	m = New(load("new name"))
+	m.RestoreViewState(saved)
+	if want := []string{"top", "leaf", "new name"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if got := m.currentNode(); got.Name() != "new name" || !isSelected(got) {
+		t.Errorf("the renamed node should be selected by its ID, got %q", got.Name())
+	}
+	if pinned := m.Pinned(); len(pinned) != 1 || !isMarked(pinned[0]) {
+		t.Errorf("the marked leaf should be pinned, got %v", names(pinned))
+	}
+
+	m.SetFilterQuery("name")
+	data, err := m.State()
+	if err != nil {
+		t.Fatalf("State() failed: %v", err)
+	}
+	m = New(load("new name"))
+	if err := m.RestoreState(data); err != nil || m.FilterQuery() != "name" {
+		t.Errorf("the filter should be restored, got %q, %v", m.FilterQuery(), err)
+	}
+	if err := m.RestoreState([]byte("{")); err == nil {
+		t.Errorf("invalid state should be reported")
+	}
+}
+
+func TestPaths(t *testing.T) {
+	server := tn("server", st(NodeCollapsed), c(tn("main.go")))
+	m := New(Nodes{tn("root", c(tn("cmd", st(NodeCollapsed), c(server)), tn("go.mod")))}, WithSize(40, 3))
+
+	if m.SelectPath("root/cmd/client") || m.FindPath("cmd") != nil {
+		t.Errorf("nonexistent paths shouldn't be found")
+	}
+	if !m.SelectPath("root/cmd/server/main.go") {
+		t.Fatalf("the path should be selected")
+	}
+	if got := m.currentNode(); got.Name() != "main.go" || !isSelected(got) {
+		t.Errorf("the node at the path should be selected, got %q", got.Name())
+	}
+	if !strings.Contains(m.View(), "main.go") {
+		t.Errorf("the selected node should be scrolled into view, got %q", m.View())
+	}
+
+	m.CollapsePath("/root/cmd/")
+	if got := m.currentNode(); got.Name() != "cmd" {
+		t.Errorf("the cursor should move onto the collapsed node, got %q", got.Name())
+	}
+	m.ExpandPath("root/cmd/server")
+	if want := []string{"root", "cmd", "server", "main.go", "go.mod"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+func TestRevealNode(t *testing.T) {
+	deep := tn("deep")
+	m := New(Nodes{tn("root", c(tn("a", st(NodeCollapsed), c(tn("b", st(NodeCollapsed), c(deep))))))})
+
+	if m.RevealNode(tn("stranger")) {
+		t.Errorf("nodes outside of the tree shouldn't be revealed")
+	}
+	if !m.RevealNode(deep) || m.currentNode() != deep || !isSelected(deep) {
+		t.Errorf("the node should be revealed and selected, got %q", m.currentNode().Name())
+	}
+	if want := []string{"root", "a", "b", "deep"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+func TestForest(t *testing.T) {
+	m := New(Nodes{tn("a", c(tn("a1"))), tn("b", c(tn("b1"))), tn("c")}, WithSize(40, 5))
+	rows := strings.Split(ansiSequence.ReplaceAllString(m.View(), ""), "\n")
+	want := []string{"├─ ▾ a", "│  └─   a1", "├─ ▾ b", "│  └─   b1", "└─   c"}
+	for i, w := range want {
+		if !strings.Contains(rows[i], w) {
+			t.Errorf("row %d = %q, want it to contain %q", i, rows[i], w)
+		}
+	}
+
+	m.GotoBottom()
+	if got := m.currentNode(); got.Name() != "c" || !isSelected(got) {
+		t.Errorf("the cursor should move across the top level nodes, got %q", got.Name())
+	}
+	if !m.SelectPath("b/b1") || !m.RemoveNode(m.tree[0]) {
+		t.Errorf("every top level node should be a valid root")
+	}
+}
+
+func TestStartCollapsed(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a", c(tn("a1"))), tn("b")))}, WithStartCollapsed())
+	if want := []string{"root", "a", "b"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a", st(NodeCollapsed), c(tn("a1"))), tn("b", c(tn("b1"))), tn("c", st(NodeHidden))))})
+	m.SetSortFunc(func(a, b Node) bool { return a.Name() > b.Name() })
+
+	got := []string{}
+	m.Walk(func(n Node, depth int, visible bool) bool {
+		got = append(got, fmt.Sprintf("%d:%s:%v", depth, n.Name(), visible))
+		return n.Name() != "b"
+	})
+	want := []string{"0:root:true", "1:c:false", "1:b:true", "1:a:true", "2:a1:false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk() visited %v, want %v", got, want)
+	}
+}
+
+func TestZoom(t *testing.T) {
+	deep := tn("deep", c(tn("x"), tn("y")))
+	m := New(Nodes{tn("root", c(tn("a", c(deep)), tn("b")))}, WithSize(40, 5))
+	m.Focus()
+	m.MoveDown(2)
+
+	if !m.ZoomIn() || m.Zoomed() != deep {
+		t.Fatalf("the view should be zoomed into the current node")
+	}
+	if want := []string{"deep", "x", "y"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("zoomed in AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	rows := strings.Split(ansiSequence.ReplaceAllString(m.View(), ""), "\n")
+	if want := "-rwxrwxrwx└─ ▾ deep"; !strings.HasPrefix(rows[0], want) {
+		t.Errorf("the zoomed in node should be rendered as a top level one, got %q, want %q", rows[0], want)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Z")})
+	if m.Zoomed() != nil || len(m.AllNodes()) != 6 {
+		t.Errorf("zooming out should show the whole tree, got %v", names(m.AllNodes()))
+	}
+	if got := m.currentNode(); got.Name() != "x" || !isSelected(got) {
+		t.Errorf("the cursor should stay on the same node, got %q", got.Name())
+	}
+	if m.ZoomOut() {
+		t.Errorf("there's nothing to zoom out of")
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	src := Branch("src", Leaf("main.go")).Collapsed()
+	m := New(Build(
+		Root("~").Child(src, Branch("empty"), Leaf("go.mod").Prefix("644")),
+		Leaf("other"),
+	))
+	if want := []string{"~", "src", "empty", "(empty)", "go.mod", "other"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if n := src.Node(); n.Parent().Name() != "~" || n.Children()[0].Parent() != n {
+		t.Errorf("the parents should be wired up")
+	}
+}
+
+func TestSimpleNode(t *testing.T) {
+	dir := NewSimpleNode("dir", NewSimpleNode("b"), NewSimpleNode("a"))
+	m := New(Nodes{dir})
+	m.MoveDown(1)
+	m.MoveNodeDown()
+	if !m.InsertNode(dir, 0, NewSimpleNode("new")) {
+		t.Fatalf("the node should be inserted into a SimpleNode")
+	}
+	if want := []string{"dir", "new", "a", "b"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if m.AllNodes()[1].Parent() != dir {
+		t.Errorf("the parent of the inserted node should be set")
+	}
+}
+
+func TestValueNode(t *testing.T) {
+	type info struct{ size int }
+	m := New(Nodes{NewValueNode("dir", info{}, NewValueNode("a.go", info{size: 42}))})
+	m.MoveDown(1)
+
+	if f, ok := ValueOf[info](m.SelectedNode()); !ok || f.size != 42 {
+		t.Errorf("the value of the selected node should be returned, got %v", f)
+	}
+	if _, ok := ValueOf[string](m.SelectedNode()); ok {
+		t.Errorf("values of other types shouldn't be returned")
+	}
+}
+
+type panicking struct{ *node }
+
+func (panicking) Name() string { panic("no name") }
+
+type nilChildren struct{ *node }
+
+func (nilChildren) Children() Nodes { return Nodes{nil, (*node)(nil)} }
+
+func TestBrokenNodes(t *testing.T) {
+	root := tn("root")
+	broken := panicking{tn("broken", p(root))}
+	m := New(Nodes{root, broken, nilChildren{tn("dir", st(NodeCollapsible))}}, WithSize(40, 5))
+
+	if len(m.nodes) != 3 {
+		t.Errorf("the nil children should be skipped, got %d rows", len(m.nodes))
+	}
+	if !strings.Contains(m.View(), "broken node") {
+		t.Errorf("the panicking node should be rendered as broken, got %q", m.View())
+	}
+	if err := m.Err(); err == nil || !strings.Contains(err.Error(), "no name") {
+		t.Errorf("the panic should be reported, got %v", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	a := tn("a")
+	root := tn("root", c(a, tn("b")))
+	m := New(Nodes{root}, WithSize(40, 5))
+	m.MoveDown(2)
+
+	a.name = "renamed"
+	root.children = append(root.children, tn("c", p(root)))
+	m.Refresh()
+	if want := []string{"root", "renamed", "b", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if !strings.Contains(m.View(), "renamed") {
+		t.Errorf("the changed name should be rendered, got %q", m.View())
+	}
+	if got := m.currentNode(); got.Name() != "b" || !isSelected(got) {
+		t.Errorf("the cursor should stay on the same node, got %q", got.Name())
+	}
+}
+
+func TestMutationMsgs(t *testing.T) {
+	root := tn("root", c(tn("a")))
+	m := New(Nodes{root})
+	m.Blur()
+
+	b := tn("b", p(root), c(tn("b1")))
+	for _, msg := range []tea.Msg{
+		AddNodesMsg{Parent: root, Index: -1, Nodes: Nodes{b, tn("c", p(root))}},
+		RemoveNodeMsg{Node: root.children[0]},
+		SetStateMsg{Node: b, State: NodeCollapsed | NodeMarked},
+	} {
+		m, _ = m.Update(msg)
+	}
+	if want := []string{"root", "b", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if !isMarked(b) || !isCollapsible(b) || isLastNode(b) {
+		t.Errorf("only the given states should be set, got %b", b.State())
+	}
+
+	var nilNode *node
+	for _, msg := range []tea.Msg{
+		RemoveNodeMsg{},
+		RemoveNodeMsg{Node: nilNode},
+		AddNodesMsg{Parent: root, Nodes: Nodes{nil, nilNode}},
+		AddNodesMsg{Parent: nilNode, Nodes: Nodes{tn("d")}},
+		SetStateMsg{},
+	} {
+		m, _ = m.Update(msg)
+	}
+	if want := []string{"root", "b", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("the messages with nil nodes should be ignored, got %v", names(m.AllNodes()))
+	}
+}
+
+func TestInitialPath(t *testing.T) {
+	nodes := Nodes{tn("root", c(tn("a", c(tn("a1"), tn("a2"))), tn("src", st(NodeCollapsed), c(tn("node.go")))))}
+	m := New(nodes, WithInitialPath("root/src/node.go"))
+	m.Focus()
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 40, Height: 2})
+
+	if got := m.currentNode(); got.Name() != "node.go" || !isSelected(got) {
+		t.Errorf("the node at the initial path should be selected, got %q", got.Name())
+	}
+	if !strings.Contains(m.View(), "node.go") {
+		t.Errorf("the selected node should be scrolled into view, got %q", m.View())
+	}
+	if m.Undo(); m.currentNode().Name() != "node.go" {
+		t.Errorf("the initial selection shouldn't be undone")
+	}
+}
+
+func TestUpdateNodes(t *testing.T) {
+	load := func(names ...string) Nodes {
+		children := []func(*node){}
+		for _, name := range names {
+			children = append(children, c(tn(name)))
+		}
+		return Nodes{tn("root", children...)}
+	}
+	m := New(load("a", "b", "c"), WithSize(40, 5))
+	m.Focus()
+
+	settle := m.UpdateNodes(load("a", "new", "c"))
+	if want := []string{"root", "a", "b", "new", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if !isGhost(m.AllNodes()[2]) || m.changes[m.AllNodes()[3]] != changeAdded {
+		t.Errorf("the added node should be highlighted and the removed one kept in place")
+	}
+	if settle == nil {
+		t.Fatalf("the changes should settle")
+	}
+
+	m, _ = m.Update(changesSettledMsg{id: m.changeID})
+	if want := []string{"root", "a", "new", "c"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("settled AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if len(m.changes) != 0 {
+		t.Errorf("nothing should be highlighted once settled")
+	}
+}
+
+func TestUserStates(t *testing.T) {
+	modified, ignored := UserState(0), UserState(3)
+	a := tn("a", st(modified), c(tn("a1")))
+	root := tn("root", st(ignored), c(a, tn("b")))
+	m := New(Nodes{root}, WithSize(40, 5))
+
+	m.MoveDown(1)
+	m.ToggleExpand()
+	m.ToggleMark()
+	m.Undo()
+	m.SetNodes(Nodes{root})
+	m, _ = m.Update(SetStateMsg{Node: root, State: root.State().With(NodeMarked)})
+	if !a.State().Is(modified) || !root.State().Is(ignored) {
+		t.Errorf("the user states should be kept, got %b and %b", a.State(), root.State())
+	}
+	if s := a.State().Without(modified); s&NodeUserStates != 0 {
+		t.Errorf("Without should clear the user state, got %b", s)
+	}
+	if modified&^NodeUserStates != 0 || ignored&^NodeUserStates != 0 {
+		t.Errorf("the user states should be within NodeUserStates")
+	}
+	if NodeError >= UserState(0) {
+		t.Errorf("the built-in states should be below the user states")
+	}
+	if UserState(-1) != NodeNone || UserState(4) != NodeNone {
+		t.Errorf("the user states out of range should be NodeNone, got %b and %b", UserState(-1), UserState(4))
+	}
+}
+
+func TestEventMsgs(t *testing.T) {
+	a := tn("a", c(tn("a1")))
+	root := tn("root", c(a, tn("b")))
+	m := New(Nodes{root}, WithSize(40, 5))
+	m.Focus()
+
+	var cmd tea.Cmd
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	if got := collect(cmd); !reflect.DeepEqual(got, []tea.Msg{SelectionChangedMsg{Previous: root, Current: a}}) {
+		t.Errorf("moving the cursor should emit the selection change, got %v", got)
+	}
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := collect(cmd); !reflect.DeepEqual(got, []tea.Msg{NodeCollapsedMsg{Node: a}}) {
+		t.Errorf("collapsing should be emitted, got %v", got)
+	}
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := collect(cmd); !reflect.DeepEqual(got, []tea.Msg{NodeExpandedMsg{Node: a}}) {
+		t.Errorf("expanding should be emitted, got %v", got)
+	}
+	m.Blur()
+	if _, cmd = m.Update(tea.KeyMsg{Type: tea.KeyDown}); len(collect(cmd)) != 0 {
+		t.Errorf("nothing should be emitted when nothing changes")
+	}
+}
+
+func TestEventHooks(t *testing.T) {
+	type loaded struct{ Node Node }
+	a := tn("a", st(NodeCollapsed), c(tn("a1")))
+	m := New(Nodes{tn("root", c(a))}, WithSize(40, 5))
+	m.Focus()
+	selected := Nodes{}
+	m.OnSelect = func(n Node) tea.Cmd {
+		selected = append(selected, n)
+		return nil
+	}
+	m.OnExpand = func(n Node) tea.Cmd {
+		return func() tea.Msg { return loaded{Node: n} }
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !reflect.DeepEqual(selected, Nodes{a}) {
+		t.Errorf("OnSelect should be called with the selected node, got %v", names(selected))
+	}
+	if got := collect(cmd); !reflect.DeepEqual(got, []tea.Msg{NodeExpandedMsg{Node: a}, loaded{Node: a}}) {
+		t.Errorf("the command returned by OnExpand should be batched, got %v", got)
+	}
+}
+
+// lazy loads a single child, or fails with err
+type lazy struct {
+	name     string
+	parent   Node
+	children Nodes
+	state    NodeState
+	loads    int
+	err      error
+}
+
+func (l *lazy) Name() string          { return l.name }
+func (l *lazy) Prefix() string        { return "" }
+func (l *lazy) Parent() Node          { return l.parent }
+func (l *lazy) Children() Nodes       { return l.children }
+func (l *lazy) State() NodeState      { return l.state }
+func (l *lazy) SetState(st NodeState) { l.state = st }
+func (l *lazy) SetChildren(ns Nodes)  { l.children = ns }
+func (l *lazy) LoadChildren() tea.Cmd {
+	l.loads++
+	return func() tea.Msg {
+		if l.err != nil {
+			return ChildrenLoadedMsg{Node: l, Err: l.err}
+		}
+		return ChildrenLoadedMsg{Node: l, Children: Nodes{&lazy{name: "child", parent: l}}}
+	}
+}
+
+func TestLazyLoading(t *testing.T) {
+	l := &lazy{name: "remote", state: NodeCollapsible | NodeCollapsed}
+	m := New(Nodes{tn("root", c(tn("a"))), l}, WithSize(40, 5))
+	m.Focus()
+
+	m.MoveDown(2)
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !strings.Contains(m.View(), "loading…") {
+		t.Errorf("the loading row should be shown while the children are loaded, got %q", m.View())
+	}
+	msgs := collect(cmd)
+	var loaded tea.Msg
+	for _, msg := range msgs {
+		if msg, ok := msg.(ChildrenLoadedMsg); ok {
+			loaded = msg
+		}
+	}
+	if loaded == nil || l.loads != 1 {
+		t.Fatalf("expanding should load the children once, got %v", msgs)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, _ = m.Update(loaded)
+	if want := []string{"root", "a", "remote", "child"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if m.Update(tea.KeyMsg{Type: tea.KeyEnter}); l.loads != 1 {
+		t.Errorf("the loaded children shouldn't be loaded again")
+	}
+
+	var typedNil *lazy
+	for _, msg := range []ChildrenLoadedMsg{{}, {Node: typedNil, Children: Nodes{tn("orphan")}}} {
+		m, _ = m.Update(msg)
+	}
+	if want := []string{"root", "a", "remote", "child"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("the messages without a node should be dropped, got %v", names(m.AllNodes()))
+	}
+}
+
+func TestChunks(t *testing.T) {
+	children := []*node{}
+	for i := 0; i < 5; i++ {
+		children = append(children, tn(fmt.Sprintf("n%d", i)))
+	}
+	m := New(Nodes{tn("root", c(children...))}, WithSize(40, 10), WithChunkSize(2))
+	m.Focus()
+
+	if want := []string{"root", "n0", "n1", "… load 2 more"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	m.GotoBottom()
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if want := []string{"root", "n0", "n1", "n2", "n3", "… load 1 more"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if got := m.SelectedNode(); got == nil || got.Name() != "n2" {
+		t.Errorf("the cursor should stay on the same row")
+	}
+	if !m.RevealNode(children[4]) || m.SelectedNode() != children[4] {
+		t.Errorf("revealing a node should show its chunk")
+	}
+}
+
+func TestNodeError(t *testing.T) {
+	l := &lazy{name: "remote", state: NodeCollapsible, err: errors.New("permission denied")}
+	m := New(Nodes{l}, WithSize(40, 5))
+	m.Focus()
+
+	for _, msg := range collect(m.hydrateVisible()) {
+		m, _ = m.Update(msg)
+	}
+	if !failed(l) || m.NodeErr(l) != l.err {
+		t.Errorf("the node should be marked with the error, got %b", l.State())
+	}
+	if !strings.Contains(m.View(), "permission denied") || strings.Contains(m.View(), "loading") {
+		t.Errorf("the error should be shown in place of the children, got %q", m.View())
+	}
+	if collect(m.hydrateVisible()) != nil || l.loads != 1 {
+		t.Errorf("the failed node shouldn't be loaded again until expanded")
+	}
+
+	l.err = nil
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	for _, msg := range collect(cmd) {
+		m, _ = m.Update(msg)
+	}
+	if failed(l) || m.NodeErr(l) != nil || !strings.Contains(m.View(), "child") {
+		t.Errorf("expanding the node again should retry loading, got %q", m.View())
+	}
+}
+
+type source struct {
+	nodes   Nodes
+	updates chan Patch
+}
+
+func (s source) Initial() Nodes        { return s.nodes }
+func (s source) Updates() <-chan Patch { return s.updates }
+
+// pump applies the messages of the command to the model, until it emits none
+func pump(m Model, cmd tea.Cmd) Model {
+	for _, msg := range collect(cmd) {
+		if _, ok := msg.(patchMsg); !ok {
+			continue
+		}
+		var next tea.Cmd
+		m, next = m.Update(msg)
+		m = pump(m, next)
+	}
+	return m
+}
+
+func TestSubscribe(t *testing.T) {
+	root := tn("root", c(tn("a")))
+	s := source{nodes: Nodes{root}, updates: make(chan Patch, 3)}
+	s.updates <- AddNodesMsg{Parent: root, Index: -1, Nodes: Nodes{tn("b", p(root))}}
+	s.updates <- SetStateMsg{Node: root.children[0], State: NodeMarked}
+	close(s.updates)
+
+	m := New(nil, WithSize(40, 5))
+	m = pump(m, m.Subscribe(s))
+	if want := []string{"root", "a", "b"}; !reflect.DeepEqual(names(m.AllNodes()), want) {
+		t.Errorf("AllNodes() = %v, want %v", names(m.AllNodes()), want)
+	}
+	if !isMarked(root.children[0]) {
+		t.Errorf("the patches should be applied in order")
+	}
+
+	m.Subscribe(nil)
+	if m, _ = m.Update(patchMsg{id: m.subscription.id - 1, patch: RemoveNodeMsg{Node: root}}); len(m.AllNodes()) != 3 {
+		t.Errorf("the patches of the replaced source should be ignored")
+	}
+}
+
+func TestVirtualRendering(t *testing.T) {
+	children := []*node{}
+	for i := 0; i < 1000; i++ {
+		children = append(children, tn(fmt.Sprintf("n%03d", i)))
+	}
+	m := New(Nodes{tn("root", c(children...))}, WithSize(40, 10))
+	m.Focus()
+
+	rendered := func() int {
+		return len(m.lines)
+	}
+	if got := rendered(); got > 30 {
+		t.Errorf("only the nodes near the viewport should be rendered, got %d", got)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnd})
+	if !strings.Contains(m.View(), "n999") || rendered() > 60 {
+		t.Errorf("the nodes scrolled into view should be rendered, got %q", m.View())
+	}
+	m.GotoLine(500)
+	if v := m.View(); !strings.Contains(v, "n498") || strings.Count(v, "\n") != 9 {
+		t.Errorf("the nodes scrolled into view outside of Update should be rendered, got %q", v)
+	}
+}
+
+// counted counts the calls to Name, telling how many of the nodes the model has gone through
+type counted struct {
+	*node
+	calls  *int
+	states *int
+}
+
+func (c counted) Name() string {
+	*c.calls++
+	return c.node.Name()
+}
+
+func (c counted) State() NodeState {
+	if c.states != nil {
+		*c.states++
+	}
+	return c.node.State()
+}
+
+func TestRerenderCost(t *testing.T) {
+	calls := 0
+	nodes := Nodes{}
+	for i := 0; i < 10000; i++ {
+		nodes = append(nodes, counted{tn(fmt.Sprintf("n%04d", i)), &calls, nil})
+	}
+	m := New(nodes, WithSize(40, 10))
+	m.Focus()
+
+	calls = 0
+	m.SetWidth(30)
+	m.SetLineNumbers(LineNumbersAbsolute)
+	if err := m.SetSymbols(ASCIISymbols()); err != nil {
+		t.Fatalf("SetSymbols() = %v", err)
+	}
+	if calls > 300 {
+		t.Errorf("re-rendering should go only through the nodes near the viewport, got %d calls", calls)
+	}
+	if v := m.View(); !strings.Contains(v, "n0009") || strings.Contains(v, "n0010") {
+		t.Errorf("expected the first 10 nodes in the view, got\n%s", v)
+	}
+}
+
+func TestToggleCost(t *testing.T) {
+	calls, states := 0, 0
+	nodes := Nodes{}
+	for i := 0; i < 10000; i++ {
+		nodes = append(nodes, counted{tn(fmt.Sprintf("n%04d", i), c(tn("a"), tn("b")), st(NodeCollapsed)), &calls, &states})
+	}
+	m := New(nodes, WithSize(40, 10))
+	m.Focus()
+	m.MoveDown(3)
+
+	calls, states = 0, 0
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if calls > 300 || states > 3000 {
+		t.Errorf("expanding should go only through the expanded node and the ones near the viewport, got %d names and %d states", calls, states)
+	}
+	if len(m.nodes) != 10002 || !isExpanded(m.nodes[3]) {
+		t.Fatalf("expected the children of n0003 shown, got %d nodes", len(m.nodes))
+	}
+	spliced := m.View()
+	m.refresh()
+	if v := m.View(); v != spliced {
+		t.Errorf("expected the spliced view to match the refreshed one\n%s\n%s", spliced, v)
+	}
+
+	m.Undo()
+	if len(m.nodes) != 10000 || isExpanded(m.nodes[3]) {
+		t.Errorf("undoing the expansion should collapse the node again, got %d nodes", len(m.nodes))
+	}
+}
+
+func TestFlattenCache(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a", c(tn("a1"))), tn("b")))}, WithSize(40, 5))
+	m.Focus()
+
+	flattened := m.flattened
+	m.SetWidth(30)
+	m.SetWrap(true)
+	if m.flattened != flattened || len(m.nodes) != 4 {
+		t.Errorf("the changes which don't affect the shown nodes shouldn't flatten the tree again")
+	}
+	m.MoveDown(1)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.flattened == flattened || len(m.nodes) != 3 || m.currentNode().Name() != "a" {
+		t.Errorf("collapsing should flatten the tree again, got %v", names(m.nodes))
+	}
+}
+
+func TestDeepTree(t *testing.T) {
+	const depth = 50000
+	root := tn("root")
+	n := root
+	for i := 0; i < depth; i++ {
+		c := tn(fmt.Sprintf("n%d", i), p(n))
+		n.children = []*node{c}
+		n = c
+	}
+
+	m := New(Nodes{root}, WithSize(40, 5))
+	m.Focus()
+	if len(m.AllNodes()) != depth+1 || len(Descendants(root)) != depth {
+		t.Fatalf("expected all of the %d nodes to be flattened, got %d", depth+1, len(m.AllNodes()))
+	}
+	deepest := 0
+	m.Walk(func(_ Node, depth int, _ bool) bool {
+		deepest = max(deepest, depth)
+		return true
+	})
+	if deepest != depth {
+		t.Errorf("expected to walk down to the depth %d, got %d", depth, deepest)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnd})
+	if !strings.Contains(m.View(), fmt.Sprintf("n%d", depth-1)) {
+		t.Errorf("expected the deepest node to be shown, got\n%s", m.View())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyHome})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.AllNodes()) != 1 {
+		t.Errorf("expected the collapsed root to hide everything below it, got %d nodes", len(m.AllNodes()))
+	}
+}
+
+func TestSpliceRows(t *testing.T) {
+	m := New(Nodes{tn("root", c(tn("a", c(tn("a1"), tn("a2"))), tn("b"), tn("c")))}, WithSize(40, 10))
+	m.Focus()
+	m.MoveDown(1)
+
+	// marking the rows below the subtree to tell whether they get rendered again
+	m.lines[m.nodes[4]], m.lines[m.nodes[5]] = "kept b", "kept c"
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := names(m.nodes); len(got) != 4 || m.line(2) != "kept b" || m.line(3) != "kept c" {
+		t.Errorf("expected only the collapsed subtree to be dropped, got %v %q", got, renderedRows(m))
+	}
+
+	m.lines[m.nodes[2]], m.lines[m.nodes[3]] = m.safeRender(m.nodes[2]), m.safeRender(m.nodes[3])
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	spliced := m.View()
+	m.refresh()
+	if len(m.nodes) != 6 || spliced != m.View() {
+		t.Errorf("expected the expanded subtree to be spliced in as it'd be rendered, got\n%s\nexpected\n%s", spliced, m.View())
+	}
+}
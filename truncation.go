@@ -0,0 +1,114 @@
+package tree
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// Truncation determines how the names which don't fit into the width of the tree are shortened.
+type Truncation struct {
+	// Ellipsis replaces the part of the name which was cut off, an empty one cuts it off without a trace
+	Ellipsis string
+	At       TruncateAt
+}
+
+// TruncateAt determines which part of the name is cut off.
+type TruncateAt int
+
+const (
+	// TruncateTail cuts off the end of the name
+	TruncateTail TruncateAt = iota
+	// TruncateHead cuts off the start of the name, e.g. for long paths where the file name at the end matters most
+	TruncateHead
+	// TruncateMiddle keeps both the start and the end of the name
+	TruncateMiddle
+)
+
+// DefaultTruncation returns the default truncation, cutting off the end of the names.
+func DefaultTruncation() Truncation {
+	return Truncation{
+		Ellipsis: Ellipsis,
+	}
+}
+
+// shorten truncates the text to the given width, if it's any wider.
+func (t Truncation) shorten(s string, width int) string {
+	if textWidth(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	avail := width - textWidth(t.Ellipsis)
+	if avail <= 0 {
+		// not even the ellipsis fits
+		return keepStart(s, width)
+	}
+	switch t.At {
+	case TruncateHead:
+		return t.Ellipsis + keepEnd(s, avail)
+	case TruncateMiddle:
+		head := (avail + 1) / 2
+		return keepStart(s, head) + t.Ellipsis + keepEnd(s, avail-head)
+	}
+	return keepStart(s, avail) + t.Ellipsis
+}
+
+// keepStart returns the longest start of the text which is not wider than the given width.
+// The escape sequences of the part which is cut off are kept, e.g. the resets of the colors.
+func keepStart(s string, width int) string {
+	b := strings.Builder{}
+	for _, token := range splitANSI(s) {
+		if isANSI(token) {
+			b.WriteString(token)
+			continue
+		}
+		for rest := token; rest != "" && width > 0; {
+			var cluster string
+			cluster, rest = firstCluster(rest)
+			w := textWidth(cluster)
+			if w > width {
+				// a wide character doesn't fit into the last cell
+				width = 0
+				break
+			}
+			b.WriteString(cluster)
+			width -= w
+		}
+	}
+	return b.String()
+}
+
+// keepEnd returns the longest end of the text which is not wider than the given width.
+// The escape sequences of the part which is cut off are kept, so the styling of the end stays the same.
+func keepEnd(s string, width int) string {
+	cut := textWidth(s) - width
+	if cut <= 0 {
+		return s
+	}
+	b := strings.Builder{}
+	for _, token := range splitANSI(s) {
+		if isANSI(token) || cut <= 0 {
+			b.WriteString(token)
+			continue
+		}
+		for rest := token; rest != ""; {
+			if cut <= 0 {
+				b.WriteString(rest)
+				break
+			}
+			var cluster string
+			cluster, rest = firstCluster(rest)
+			cut -= textWidth(cluster)
+		}
+	}
+	return b.String()
+}
+
+// firstCluster splits off the first cluster of runes displayed as a single character,
+// e.g. a letter with combining accents or an emoji with a skin tone, so it's never cut in half.
+func firstCluster(s string) (string, string) {
+	cluster, rest, _, _ := uniseg.FirstGraphemeClusterInString(s, -1)
+	return cluster, rest
+}
@@ -0,0 +1,147 @@
+package tree
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// undoLimit is the maximum number of operations which can be undone.
+const undoLimit = 100
+
+// undoableStates are the states restored by Undo and Redo.
+const undoableStates = NodeCollapsed | NodeMarked | NodeHidden
+
+// snapshot captures the state of every node and the cursor position, along with
+// the filter, since the NodeHidden states depend on it. The nodes hidden by the
+// predicate are left out, they follow SetHiddenPredicate and ToggleHidden instead.
+type snapshot struct {
+	nodes  Nodes
+	states []NodeState
+	cursor Node
+
+	filter      func(Node) bool
+	filterQuery string
+	filtered    Nodes
+}
+
+func (m Model) snapshot() snapshot {
+	return m.snapshotOf(m.tree.all())
+}
+
+// snapshotOf captures the state of the given nodes only, e.g. of the one being expanded,
+// sparing going through the whole tree for the operations which change a single node.
+func (m Model) snapshotOf(ns Nodes) snapshot {
+	s := snapshot{
+		nodes:       ns,
+		states:      make([]NodeState, len(ns)),
+		filter:      m.filter,
+		filterQuery: m.filterQuery,
+		// the filter starts over with a new slice, so it's never appended to
+		filtered: m.filtered[:len(m.filtered):len(m.filtered)],
+	}
+	byPredicate := m.hiddenByPredicate()
+	for i, n := range ns {
+		s.states[i] = n.State()
+		if byPredicate[n] {
+			s.states[i] &^= NodeHidden
+		}
+	}
+	if len(m.nodes) > 0 {
+		s.cursor = m.currentNode()
+	}
+	return s
+}
+
+// saveUndo records the current state so the operation which is about to be
+// applied can be undone.
+func (m *Model) saveUndo() {
+	m.pushUndo(m.snapshot())
+}
+
+// saveUndoOf records the current state of the node, for the operations changing only it.
+func (m *Model) saveUndoOf(n Node) {
+	m.pushUndo(m.snapshotOf(Nodes{n}))
+}
+
+// pushUndo records the given state as the one before the last operation.
+// A new operation discards everything that could have been redone.
+func (m *Model) pushUndo(s snapshot) {
+	m.undo = push(m.undo, s)
+	m.redo = nil
+}
+
+func push(stack []snapshot, s snapshot) []snapshot {
+	stack = append(stack, s)
+	if overflow := len(stack) - undoLimit; overflow > 0 {
+		stack = stack[overflow:]
+	}
+	return stack
+}
+
+// restore applies the states and the filter from the snapshot and moves the cursor back to where it was.
+func (m *Model) restore(s snapshot) {
+	m.filter, m.filterQuery = s.filter, s.filterQuery
+	m.filtered = s.filtered
+	m.restoreStates(s, undoableStates)
+}
+
+// restoreStates applies only the given states from the snapshot and moves the cursor back to where it was.
+func (m *Model) restoreStates(s snapshot, states NodeState) {
+	byPredicate := m.hiddenByPredicate()
+	for i, n := range s.nodes {
+		if states.Is(NodeCollapsed) {
+			m.setCollapsed(n, s.states[i].Is(NodeCollapsed))
+		}
+		restored := s.states[i] & states
+		if byPredicate[n] {
+			restored |= n.State() & NodeHidden
+		}
+		n.SetState(n.State()&^states | restored)
+	}
+	m.refreshAndSelect(s.cursor)
+}
+
+// Undo reverts the last expand, collapse, mark or cursor jump operation.
+func (m *Model) Undo() tea.Cmd {
+	if len(m.undo) == 0 {
+		return noop
+	}
+	s := m.undo[len(m.undo)-1]
+	m.undo = m.undo[:len(m.undo)-1]
+	m.redo = push(m.redo, m.snapshotOf(s.nodes))
+	m.restore(s)
+	return noop
+}
+
+// Redo reapplies the last operation reverted by Undo.
+func (m *Model) Redo() tea.Cmd {
+	if len(m.redo) == 0 {
+		return noop
+	}
+	s := m.redo[len(m.redo)-1]
+	m.redo = m.redo[:len(m.redo)-1]
+	m.undo = push(m.undo, m.snapshotOf(s.nodes))
+	m.restore(s)
+	return noop
+}
+
+// saveExpansion records the expansion state of the tree, before the view is
+// restructured by expanding or collapsing nodes.
+func (m *Model) saveExpansion() {
+	m.expansionUndo = push(m.expansionUndo, m.snapshot())
+}
+
+// saveExpansionOf records the expansion state of the node, before it's expanded or collapsed.
+func (m *Model) saveExpansionOf(n Node) {
+	m.expansionUndo = push(m.expansionUndo, m.snapshotOf(Nodes{n}))
+}
+
+// UndoExpansion reverts the last expand or collapse, e.g. re-opens everything
+// a stray CollapseAll has closed. Unlike Undo, it only restores which nodes
+// are expanded, leaving the marks and everything else as they are.
+func (m *Model) UndoExpansion() tea.Cmd {
+	if len(m.expansionUndo) == 0 {
+		return noop
+	}
+	s := m.expansionUndo[len(m.expansionUndo)-1]
+	m.expansionUndo = m.expansionUndo[:len(m.expansionUndo)-1]
+	m.restoreStates(s, NodeCollapsed)
+	return noop
+}
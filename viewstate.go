@@ -0,0 +1,112 @@
+package tree
+
+import "encoding/json"
+
+// ViewState is the context of the user which outlives the nodes, keyed by their identities,
+// see Identifiable. It can be saved, e.g. on exit, and restored once the nodes are loaded again.
+type ViewState struct {
+	// Expanded and Collapsed are the collapsible nodes in the respective state
+	Expanded  []string `json:"expanded,omitempty"`
+	Collapsed []string `json:"collapsed,omitempty"`
+	// Marked are the nodes marked by the user
+	Marked []string `json:"marked,omitempty"`
+	// Pinned are the pinned nodes, in the order they were pinned
+	Pinned []string `json:"pinned,omitempty"`
+	// Selected is the node under the cursor
+	Selected string `json:"selected,omitempty"`
+	// YOffset is how far the tree is scrolled, see SetYOffset
+	YOffset int `json:"y_offset,omitempty"`
+	// Filter is the query of the filter, see SetFilterQuery
+	Filter string `json:"filter,omitempty"`
+}
+
+// State returns the view state encoded as JSON, e.g. for persisting it between the sessions,
+// see ViewState.
+func (m Model) State() ([]byte, error) {
+	return json.Marshal(m.ViewState())
+}
+
+// RestoreState restores the view state encoded by State, see RestoreViewState.
+func (m *Model) RestoreState(data []byte) error {
+	var s ViewState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	m.RestoreViewState(s)
+	return nil
+}
+
+// ViewState returns the expanded, marked, pinned and selected nodes, how far the tree
+// is scrolled and the filter query, see RestoreViewState.
+func (m Model) ViewState() ViewState {
+	s := ViewState{YOffset: m.view.YOffset, Filter: m.filterQuery}
+	for _, n := range m.tree.all() {
+		id := nodeID(n)
+		if isCollapsible(n) {
+			if isExpanded(n) {
+				s.Expanded = append(s.Expanded, id)
+			} else {
+				s.Collapsed = append(s.Collapsed, id)
+			}
+		}
+		if isMarked(n) {
+			s.Marked = append(s.Marked, id)
+		}
+	}
+	for _, n := range m.pinned {
+		s.Pinned = append(s.Pinned, nodeID(n))
+	}
+	if n := m.selectedNode(); n != nil && !isPlaceholder(n) {
+		s.Selected = nodeID(n)
+	}
+	return s
+}
+
+// RestoreViewState applies the state saved with ViewState to the matching nodes.
+// The nodes which aren't mentioned in it, e.g. the ones added since it was saved,
+// keep their states. The cursor stays where it is if the selected node is gone.
+func (m *Model) RestoreViewState(s ViewState) {
+	if s.Filter != m.filterQuery {
+		// the filter expands the ancestors of the matches, the saved states take precedence
+		m.SetFilterQuery(s.Filter)
+	}
+	byID := map[string]Node{}
+	for _, n := range m.tree.all() {
+		byID[nodeID(n)] = n
+	}
+	set := func(ids []string, st NodeState, on bool) {
+		for _, id := range ids {
+			n, ok := byID[id]
+			if !ok {
+				continue
+			}
+			if on {
+				n.SetState(n.State() | st)
+			} else {
+				n.SetState(n.State() &^ st)
+			}
+		}
+	}
+	set(s.Expanded, NodeCollapsed, false)
+	set(s.Collapsed, NodeCollapsed, true)
+	for _, n := range m.tree.all() {
+		n.SetState(n.State() &^ NodeMarked)
+	}
+	set(s.Marked, NodeMarked, true)
+
+	m.pinned = Nodes{}
+	for _, id := range s.Pinned {
+		if n, ok := byID[id]; ok {
+			m.pinned = append(m.pinned, n)
+		}
+	}
+	m.layout()
+
+	target := m.currentNode()
+	if n, ok := byID[s.Selected]; ok {
+		target = n
+	}
+	m.refreshAndSelect(target)
+	m.setYOffset(s.YOffset)
+	m.scrollToCursor()
+}
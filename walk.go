@@ -0,0 +1,21 @@
+package tree
+
+// Walk calls fn for every node of the tree, depth-first, in the order they're rendered in,
+// i.e. respecting the sort function, see SetSortFunc. Collapsed and hidden nodes are
+// visited too, visible reports whether the node is rendered, i.e. it isn't hidden and
+// all of its ancestors are expanded. Returning false skips the descendants of the node.
+func (m Model) Walk(fn func(n Node, depth int, visible bool) bool) {
+	// whether the children at the given depth are rendered, overwritten by every next sibling of their parent
+	shownAt := []bool{true}
+	traverse(m.tree.sorted(m.less), 0, func(n Node, _ int, _ Nodes, depth int) Nodes {
+		if isNil(n) {
+			return nil
+		}
+		shown := shownAt[depth] && !isHidden(n)
+		if !fn(n, depth, shown) {
+			return nil
+		}
+		shownAt = append(shownAt[:depth+1], shown && isExpanded(n))
+		return n.Children().sorted(m.less)
+	})
+}
@@ -0,0 +1,41 @@
+package tree
+
+import (
+	"strings"
+
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/muesli/reflow/wrap"
+)
+
+// WithWrap wraps the names instead of truncating them, see SetWrap.
+func WithWrap() Option {
+	return func(m *Model) {
+		m.wrap = true
+	}
+}
+
+// SetWrap toggles wrapping the names which don't fit into the width of the tree
+// onto continuation rows aligned with the name, instead of truncating them, see Truncation.
+// Words longer than the width are broken up.
+func (m *Model) SetWrap(on bool) {
+	m.wrap = on
+	m.rerender()
+}
+
+// fitLines makes every line of a name fit into the given width,
+// by either wrapping or truncating it.
+func (m Model) fitLines(lines []string, width int) []string {
+	res := make([]string, 0, len(lines))
+	for _, l := range lines {
+		switch {
+		case textWidth(l) <= width:
+			res = append(res, l)
+		case m.wrap:
+			wrapped := wrap.String(wordwrap.String(l, width), width)
+			res = append(res, strings.Split(wrapped, "\n")...)
+		default:
+			res = append(res, m.Truncation.shorten(l, width))
+		}
+	}
+	return res
+}
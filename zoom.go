@@ -0,0 +1,52 @@
+package tree
+
+// ZoomIn re-roots the view at the node under the cursor, showing only its subtree as if
+// it was the only top level node, e.g. for focusing on a deep directory. The zoomed in
+// nodes are stacked, ZoomOut goes back to the previous one.
+// It reports whether the view was zoomed in, i.e. the node is collapsible.
+func (m *Model) ZoomIn() bool {
+	n := m.SelectedNode()
+	if n == nil || !isCollapsible(n) || m.zoomed() == n {
+		return false
+	}
+	m.setCollapsed(n, false)
+	m.zoom = append(m.zoom, n)
+	m.refreshAndSelect(n)
+	return true
+}
+
+// ZoomOut goes back to the view before the last ZoomIn, keeping the cursor on the same node.
+// It reports whether the view was zoomed in.
+func (m *Model) ZoomOut() bool {
+	if len(m.zoom) == 0 {
+		return false
+	}
+	m.zoom = m.zoom[:len(m.zoom)-1]
+	m.refreshAndSelect(m.currentNode())
+	return true
+}
+
+// Zoomed returns the node the view is zoomed into, or nil if it isn't, see ZoomIn.
+func (m Model) Zoomed() Node {
+	return m.zoomed()
+}
+
+func (m Model) zoomed() Node {
+	if len(m.zoom) == 0 {
+		return nil
+	}
+	return m.zoom[len(m.zoom)-1]
+}
+
+// roots returns the top level nodes of the view, i.e. the zoomed in node, if any.
+func (m Model) roots() Nodes {
+	if n := m.zoomed(); n != nil {
+		return Nodes{n}
+	}
+	return m.tree
+}
+
+// zoomDepth returns the depth of the zoomed in node, which is rendered as a top level one.
+func (m Model) zoomDepth() int {
+	return getDepth(m.zoomed())
+}